@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
 	"crypto/tls"
 	"encoding/binary"
@@ -50,29 +51,56 @@ type Batch struct {
 	Vectors [][]float32
 	Offset  int
 	Filters []int
+	// Optional properties per vector, e.g. the columns of a Parquet dataset
+	Properties []map[string]interface{}
+}
+
+// Number of objects in the batch, batches of keyword corpora carry
+// properties only
+func (b *Batch) len() int {
+	if b.Vectors != nil {
+		return len(b.Vectors)
+	}
+	return len(b.Properties)
 }
 
 // Weaviate https://github.com/weaviate/weaviate-chaos-engineering/tree/main/apps/ann-benchmarks style format
 // mixed camel / snake case for compatibility
 type ResultsJSONBenchmark struct {
-	Api              string  `json:"api"`
-	Ef               int     `json:"ef"`
-	EfConstruction   int     `json:"efConstruction"`
-	MaxConnections   int     `json:"maxConnections"`
-	Mean             float64 `json:"meanLatency"`
-	P99Latency       float64 `json:"p99Latency"`
-	QueriesPerSecond float64 `json:"qps"`
-	Shards           int     `json:"shards"`
-	Parallelization  int     `json:"parallelization"`
-	Limit            int     `json:"limit"`
-	ImportTime       float64 `json:"importTime"`
-	RunID            string  `json:"run_id"`
-	Dataset          string  `json:"dataset_file"`
-	Recall           float64 `json:"recall"`
-	HeapAllocBytes   float64 `json:"heap_alloc_bytes"`
-	HeapInuseBytes   float64 `json:"heap_inuse_bytes"`
-	HeapSysBytes     float64 `json:"heap_sys_bytes"`
-	Timestamp        string  `json:"timestamp"`
+	Api              string            `json:"api"`
+	Ef               int               `json:"ef"`
+	EfConstruction   int               `json:"efConstruction"`
+	MaxConnections   int               `json:"maxConnections"`
+	Mean             float64           `json:"meanLatency"`
+	P99Latency       float64           `json:"p99Latency"`
+	P999Latency      float64           `json:"p999Latency"`
+	QueriesPerSecond float64           `json:"qps"`
+	Shards           int               `json:"shards"`
+	Parallelization  int               `json:"parallelization"`
+	Limit            int               `json:"limit"`
+	ImportTime       float64           `json:"importTime"`
+	RunID            string            `json:"run_id"`
+	Dataset          string            `json:"dataset_file"`
+	Recall           *float64          `json:"recall,omitempty"`
+	DistanceError    *float64          `json:"relativeDistanceError,omitempty"`
+	NDCG             *float64          `json:"ndcg,omitempty"`
+	MRR              *float64          `json:"mrr,omitempty"`
+	MAP              *float64          `json:"map,omitempty"`
+	Precision        *float64          `json:"precision,omitempty"`
+	HeapAllocBytes   float64           `json:"heap_alloc_bytes"`
+	HeapInuseBytes   float64           `json:"heap_inuse_bytes"`
+	HeapSysBytes     float64           `json:"heap_sys_bytes"`
+	Timestamp        string            `json:"timestamp"`
+	TargetQPS        float64           `json:"targetQps,omitempty"`
+	Failed           int               `json:"failed"`
+	ErrorRate        float64           `json:"errorRate"`
+	BudgetExceeded   bool              `json:"budgetExceeded,omitempty"`
+	Errors           map[string]int    `json:"errors,omitempty"`
+	LatencyHistogram []histogramBucket `json:"latencyHistogram,omitempty"`
+	Import           *ImportResults    `json:"import,omitempty"`
+	QueryType        string            `json:"queryType"`
+	Alpha            float64           `json:"alpha,omitempty"`
+	FusionType       string            `json:"fusionType,omitempty"`
 }
 
 // Convert an int to a uuid formatted string
@@ -98,10 +126,10 @@ func intFromUUID(uuidStr string) int {
 }
 
 // Writes a single batch of vectors to Weaviate using gRPC
-func writeChunk(chunk *Batch, client *weaviategrpc.WeaviateClient, cfg *Config) {
-	objects := make([]*weaviategrpc.BatchObject, len(chunk.Vectors))
+func writeChunk(chunk *Batch, client *weaviategrpc.WeaviateClient, cfg *Config, stats *importStats) {
+	objects := make([]*weaviategrpc.BatchObject, chunk.len())
 
-	for i, vector := range chunk.Vectors {
+	for i := range objects {
 		objects[i] = &weaviategrpc.BatchObject{
 			Uuid:       uuidFromInt(i + chunk.Offset + cfg.Offset),
 			Collection: cfg.ClassName,
@@ -109,41 +137,26 @@ func writeChunk(chunk *Batch, client *weaviategrpc.WeaviateClient, cfg *Config)
 		if cfg.Tenant != "" {
 			objects[i].Tenant = cfg.Tenant
 		}
-		if cfg.MultiVectorDimensions > 0 {
-			if len(vector)%cfg.MultiVectorDimensions != 0 {
-				log.Fatalf("Vector length %d is not a multiple of dimensions %d",
-					len(vector), cfg.MultiVectorDimensions)
-			}
-			rows := len(vector) / cfg.MultiVectorDimensions
-
-			multiVec := make([][]float32, rows)
-			for i := 0; i < rows; i++ {
-				start := i * cfg.MultiVectorDimensions
-				end := start + cfg.MultiVectorDimensions
-				multiVec[i] = vector[start:end]
-			}
-			objects[i].Vectors = []*weaviategrpc.Vectors{{
-				Name:        "multivector",
-				VectorBytes: byteops.Fp32SliceOfSlicesToBytes(multiVec),
-				Type:        weaviategrpc.Vectors_VECTOR_TYPE_MULTI_FP32,
-			}}
-		} else {
-			objects[i].VectorBytes = encodeVector(vector)
+		if chunk.Vectors != nil {
+			setObjectVector(objects[i], chunk.Vectors[i], cfg)
 		}
-		if cfg.NamedVector != "" {
-			vectors := make([]*weaviategrpc.Vectors, 1)
-			vectors[0] = &weaviategrpc.Vectors{
-				VectorBytes: encodeVector(vector),
-				Name:        cfg.NamedVector,
+		properties := map[string]interface{}{}
+		if chunk.Properties != nil {
+			for name, value := range chunk.Properties[i] {
+				properties[name] = value
 			}
-			objects[i].Vectors = vectors
 		}
 		if cfg.Filter {
-			nonRefProperties, err := structpb.NewStruct(map[string]interface{}{
-				"category": strconv.Itoa(chunk.Filters[i]),
-			})
+			properties["category"] = strconv.Itoa(chunk.Filters[i])
+		}
+		for _, cardinality := range cfg.SelectivityCardinalities {
+			properties[cardinalityProperty(cardinality)] = strconv.Itoa(
+				cardinalityValue(chunk.Offset+i, cardinality, trainCardinalitySalt))
+		}
+		if len(properties) > 0 {
+			nonRefProperties, err := structpb.NewStruct(properties)
 			if err != nil {
-				log.Fatalf("Error creating filtered struct: %v", err)
+				log.Fatalf("Error creating properties struct: %v", err)
 			}
 			objects[i].Properties = &weaviategrpc.BatchObject_Properties{
 				NonRefProperties: nonRefProperties,
@@ -165,18 +178,63 @@ func writeChunk(chunk *Batch, client *weaviategrpc.WeaviateClient, cfg *Config)
 		ctx = metadata.NewOutgoingContext(ctx, md)
 	}
 
+	start := time.Now()
 	response, err := (*client).BatchObjects(ctx, batchRequest)
 	if err != nil {
 		log.Fatalf("could not send batch: %v", err)
 	}
+	took := time.Since(start)
+	benchmarkerMetrics.importBatchDuration.Observe(took.Seconds())
+	benchmarkerMetrics.importedObjects.Add(float64(len(objects)))
 
+	var objectErrors []string
 	for _, result := range response.GetErrors() {
 		if result.Error != "" {
+			objectErrors = append(objectErrors, result.Error)
 			log.Printf("Error for index %d: %s", result.Index, result.Error)
 		} else {
 			log.Printf("Successfully processed object at index %d", result.Index)
 		}
 	}
+	stats.recordBatch(took, len(objects), objectErrors)
+	if len(objectErrors) > 0 {
+		benchmarkerMetrics.importBatchErrors.Inc()
+		benchmarkerMetrics.importObjectErrors.Add(float64(len(objectErrors)))
+	}
+}
+
+// Set the vector of a batch object, as a multi-vector or named vector if
+// configured
+func setObjectVector(object *weaviategrpc.BatchObject, vector []float32, cfg *Config) {
+	if cfg.MultiVectorDimensions > 0 {
+		if len(vector)%cfg.MultiVectorDimensions != 0 {
+			log.Fatalf("Vector length %d is not a multiple of dimensions %d",
+				len(vector), cfg.MultiVectorDimensions)
+		}
+		rows := len(vector) / cfg.MultiVectorDimensions
+
+		multiVec := make([][]float32, rows)
+		for i := 0; i < rows; i++ {
+			start := i * cfg.MultiVectorDimensions
+			end := start + cfg.MultiVectorDimensions
+			multiVec[i] = vector[start:end]
+		}
+		object.Vectors = []*weaviategrpc.Vectors{{
+			Name:        "multivector",
+			VectorBytes: byteops.Fp32SliceOfSlicesToBytes(multiVec),
+			Type:        weaviategrpc.Vectors_VECTOR_TYPE_MULTI_FP32,
+		}}
+	} else {
+		object.VectorBytes = encodeVector(vector)
+	}
+	if cfg.NamedVector != "" {
+		vectors := make([]*weaviategrpc.Vectors, 1)
+		vectors[0] = &weaviategrpc.Vectors{
+			VectorBytes: encodeVector(vector),
+			Name:        cfg.NamedVector,
+		}
+		object.Vectors = vectors
+	}
 }
 
 func createClient(cfg *Config) *weaviate.Client {
@@ -455,18 +513,10 @@ func waitReady(cfg *Config, client *weaviate.Client, indexStart time.Time, maxDu
 
 	log.Infof("Waiting for queue to be empty\n")
 	for current.Sub(start) < maxDuration {
-		nodesStatus, err := client.Cluster().NodesStatusGetter().WithOutput("verbose").Do(context.Background())
+		totalShardQueue, err := vectorQueueLength(cfg, client)
 		if err != nil {
 			panic(err)
 		}
-		totalShardQueue := int64(0)
-		for _, n := range nodesStatus.Nodes {
-			for _, s := range n.Shards {
-				if s.Class == cfg.ClassName && s.VectorQueueLength > 0 {
-					totalShardQueue += s.VectorQueueLength
-				}
-			}
-		}
 		if totalShardQueue < minQueueSize {
 			log.WithFields(log.Fields{"duration": current.Sub(start)}).Printf("Queue ready\n")
 			log.WithFields(log.Fields{"duration": current.Sub(indexStart)}).Printf("Total load and queue ready\n")
@@ -479,6 +529,23 @@ func waitReady(cfg *Config, client *weaviate.Client, indexStart time.Time, maxDu
 	return current
 }
 
+// Sum of the vector queue lengths of all shards of the class
+func vectorQueueLength(cfg *Config, client *weaviate.Client) (int64, error) {
+	nodesStatus, err := client.Cluster().NodesStatusGetter().WithOutput("verbose").Do(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	totalShardQueue := int64(0)
+	for _, n := range nodesStatus.Nodes {
+		for _, s := range n.Shards {
+			if s.Class == cfg.ClassName && s.VectorQueueLength > 0 {
+				totalShardQueue += s.VectorQueueLength
+			}
+		}
+	}
+	return totalShardQueue, nil
+}
+
 // Update ef parameter on the Weaviate schema
 func enableCompression(cfg *Config, client *weaviate.Client, dimensions uint, compressionType CompressionType) {
 	classConfig, err := client.Schema().ClassGetter().WithClassName(cfg.ClassName).Do(context.Background())
@@ -568,7 +635,7 @@ func enableCompression(cfg *Config, client *weaviate.Client, dimensions uint, co
 	}
 }
 
-func convert1DChunk[D float32 | float64](input []D, dimensions int, batchRows int) [][]float32 {
+func convert1DChunk[D float32 | float64 | int8 | uint8](input []D, dimensions int, batchRows int) [][]float32 {
 	chunkData := make([][]float32, batchRows)
 	for i := range chunkData {
 		chunkData[i] = make([]float32, dimensions)
@@ -587,12 +654,105 @@ func getHDF5ByteSize(dataset *hdf5.Dataset) uint {
 
 	// log.WithFields(log.Fields{"size": datatype.Size()}).Printf("Parsing HDF5 byte format\n")
 	byteSize := datatype.Size()
-	if byteSize != 4 && byteSize != 8 && byteSize != 16 {
-		log.Fatalf("Unable to load dataset with byte size %d\n", byteSize)
+	if byteSize != 4 && byteSize != 8 {
+		log.Fatalf("Unable to load dataset with byte size %d, expected 4 or 8\n", byteSize)
 	}
 	return byteSize
 }
 
+// Component types of hdf5 vector datasets
+const (
+	hdf5Float16  = "float16"
+	hdf5BFloat16 = "bfloat16"
+	hdf5Float32  = "float32"
+	hdf5Float64  = "float64"
+	hdf5Int8     = "int8"
+	hdf5Uint8    = "uint8"
+)
+
+// The component type of a vector dataset. HDF5 has no bfloat16 type, these
+// are stored as 2-byte floats or uint16 and need cfg.BFloat16 to be decoded.
+func getHDF5VectorType(dataset *hdf5.Dataset, cfg *Config) string {
+	datatype, err := dataset.Datatype()
+	if err != nil {
+		log.Fatalf("Unable to read datatype: %v", err)
+	}
+	defer datatype.Close()
+
+	class, size := datatype.Class(), datatype.Size()
+	switch {
+	case class == hdf5.T_FLOAT && size == 2 && !cfg.BFloat16:
+		return hdf5Float16
+	case (class == hdf5.T_FLOAT || class == hdf5.T_INTEGER) && size == 2 && cfg.BFloat16:
+		return hdf5BFloat16
+	case class == hdf5.T_FLOAT && size == 4:
+		return hdf5Float32
+	case class == hdf5.T_FLOAT && size == 8:
+		return hdf5Float64
+	case class == hdf5.T_INTEGER && size == 1:
+		if datatype.Equal(hdf5.T_STD_I8LE) || datatype.Equal(hdf5.T_STD_I8BE) {
+			return hdf5Int8
+		}
+		return hdf5Uint8
+	}
+
+	log.Fatalf("Unable to load vectors of %d-byte %s type, supported are float16, bfloat16 (--bfloat16), "+
+		"float32, float64, int8 and uint8", size, hdf5ClassName(class))
+	return ""
+}
+
+func hdf5ClassName(class hdf5.TypeClass) string {
+	switch class {
+	case hdf5.T_FLOAT:
+		return "float"
+	case hdf5.T_INTEGER:
+		return "integer"
+	default:
+		return fmt.Sprintf("class %d", class)
+	}
+}
+
+// Read the selected rows of a vector dataset and convert them to float32
+func readHdf5Vectors(dataset *hdf5.Dataset, vectorType string, memspace, filespace *hdf5.Dataspace,
+	rows, dimensions uint,
+) ([][]float32, error) {
+	switch vectorType {
+	case hdf5Float16, hdf5BFloat16:
+		raw := make([]uint16, rows*dimensions)
+		if err := dataset.ReadSubset(&raw, memspace, filespace); err != nil {
+			return nil, err
+		}
+		converted := make([]float32, len(raw))
+		for i, v := range raw {
+			if vectorType == hdf5BFloat16 {
+				converted[i] = math.Float32frombits(uint32(v) << 16)
+			} else {
+				converted[i] = float16ToFloat32(v)
+			}
+		}
+		return convert1DChunk[float32](converted, int(dimensions), int(rows)), nil
+	case hdf5Float32:
+		return readHdf5Chunk[float32](dataset, memspace, filespace, rows, dimensions)
+	case hdf5Float64:
+		return readHdf5Chunk[float64](dataset, memspace, filespace, rows, dimensions)
+	case hdf5Int8:
+		return readHdf5Chunk[int8](dataset, memspace, filespace, rows, dimensions)
+	case hdf5Uint8:
+		return readHdf5Chunk[uint8](dataset, memspace, filespace, rows, dimensions)
+	}
+	return nil, fmt.Errorf("unsupported vector type %s", vectorType)
+}
+
+func readHdf5Chunk[D float32 | float64 | int8 | uint8](dataset *hdf5.Dataset, memspace, filespace *hdf5.Dataspace,
+	rows, dimensions uint,
+) ([][]float32, error) {
+	chunkData1D := make([]D, rows*dimensions)
+	if err := dataset.ReadSubset(&chunkData1D, memspace, filespace); err != nil {
+		return nil, err
+	}
+	return convert1DChunk[D](chunkData1D, int(dimensions), int(rows)), nil
+}
+
 // Load a large dataset from an hdf5 file and stream it to Weaviate
 // startOffset and maxRecords are ignored if equal to 0
 func loadHdf5Streaming(dataset *hdf5.Dataset, chunks chan<- Batch, cfg *Config, startOffset uint, maxRecords uint, filters []int) {
@@ -603,7 +763,7 @@ func loadHdf5Streaming(dataset *hdf5.Dataset, chunks chan<- Batch, cfg *Config,
 		log.Fatal("expected 2 dimensions")
 	}
 
-	byteSize := getHDF5ByteSize(dataset)
+	vectorType := getHDF5VectorType(dataset, cfg)
 
 	rows := dims[0]
 	dimensions := dims[1]
@@ -620,7 +780,7 @@ func loadHdf5Streaming(dataset *hdf5.Dataset, chunks chan<- Batch, cfg *Config,
 
 	batchSize := uint(cfg.BatchSize)
 
-	log.WithFields(log.Fields{"rows": rows, "dimensions": dimensions}).Printf(
+	log.WithFields(log.Fields{"rows": rows, "dimensions": dimensions, "type": vectorType}).Printf(
 		"Reading HDF5 dataset")
 
 	memspace, err := hdf5.CreateSimpleDataspace([]uint{batchSize, dimensions}, []uint{batchSize, dimensions})
@@ -648,32 +808,10 @@ func loadHdf5Streaming(dataset *hdf5.Dataset, chunks chan<- Batch, cfg *Config,
 			log.Fatalf("Error selecting hyperslab: %v", err)
 		}
 
-		var chunkData [][]float32
-
-		if byteSize == 4 {
-			chunkData1D := make([]float32, batchRows*dimensions)
-
-			if err := dataset.ReadSubset(&chunkData1D, memspace, dataspace); err != nil {
-				log.Printf("BatchRows = %d, i = %d, rows = %d", batchRows, i, rows)
-				log.Fatalf("Error reading subset: %v", err)
-			}
-
-			chunkData = convert1DChunk[float32](chunkData1D, int(dimensions), int(batchRows))
-
-		} else if byteSize == 8 {
-			chunkData1D := make([]float64, batchRows*dimensions)
-
-			if err := dataset.ReadSubset(&chunkData1D, memspace, dataspace); err != nil {
-				log.Printf("BatchRows = %d, i = %d, rows = %d", batchRows, i, rows)
-				log.Fatalf("Error reading subset: %v", err)
-			}
-
-			chunkData = convert1DChunk[float64](chunkData1D, int(dimensions), int(batchRows))
-
-		}
-
-		if (i+batchRows)%10000 == 0 {
-			log.Printf("Imported %d/%d rows", i+batchRows, rows)
+		chunkData, err := readHdf5Vectors(dataset, vectorType, memspace, dataspace, batchRows, dimensions)
+		if err != nil {
+			log.Printf("BatchRows = %d, i = %d, rows = %d", batchRows, i, rows)
+			log.Fatalf("Error reading subset: %v", err)
 		}
 
 		filter := []int{}
@@ -685,8 +823,8 @@ func loadHdf5Streaming(dataset *hdf5.Dataset, chunks chan<- Batch, cfg *Config,
 	}
 }
 
-// Read an entire dataset from an hdf5 file at once
-func loadHdf5Float32(file *hdf5.File, name string, cfg *Config) [][]float32 {
+// Read the selected rows of a vector dataset, all of them if rows is nil
+func loadHdf5Float32(file *hdf5.File, name string, cfg *Config, selected []int) [][]float32 {
 	dataset, err := file.OpenDataset(name)
 	if err != nil {
 		log.Fatalf("Error opening loadHdf5Float32 dataset: %v", err)
@@ -695,7 +833,7 @@ func loadHdf5Float32(file *hdf5.File, name string, cfg *Config) [][]float32 {
 	dataspace := dataset.Space()
 	dims, _, _ := dataspace.SimpleExtentDims()
 
-	byteSize := getHDF5ByteSize(dataset)
+	vectorType := getHDF5VectorType(dataset, cfg)
 
 	var rows uint
 	var dimensions uint
@@ -710,21 +848,47 @@ func loadHdf5Float32(file *hdf5.File, name string, cfg *Config) [][]float32 {
 		dimensions = dims[1]
 	}
 
-	var chunkData [][]float32
-
-	if byteSize == 4 {
-		chunkData1D := make([]float32, rows*dimensions)
-		dataset.Read(&chunkData1D)
-		chunkData = convert1DChunk[float32](chunkData1D, int(dimensions), int(rows))
-	} else if byteSize == 8 {
-		chunkData1D := make([]float64, rows*dimensions)
-		dataset.Read(&chunkData1D)
-		chunkData = convert1DChunk[float64](chunkData1D, int(dimensions), int(rows))
+	chunkData, err := readRowRuns(selected, int(rows), func(start, count int) ([][]float32, error) {
+		return readHdf5Rows(dataset, uint(start), uint(count), func(memspace, filespace *hdf5.Dataspace) ([][]float32, error) {
+			return readHdf5Vectors(dataset, vectorType, memspace, filespace, uint(count), dimensions)
+		})
+	})
+	if err != nil {
+		log.Fatalf("Error reading %s dataset: %v", name, err)
 	}
 
 	return chunkData
 }
 
+// Select count rows starting at start in the file and read them with read
+func readHdf5Rows[T any](dataset *hdf5.Dataset, start, count uint,
+	read func(memspace, filespace *hdf5.Dataspace) (T, error),
+) (T, error) {
+	var empty T
+	filespace := dataset.Space()
+	defer filespace.Close()
+	dims, _, err := filespace.SimpleExtentDims()
+	if err != nil {
+		return empty, err
+	}
+
+	offset := make([]uint, len(dims))
+	offset[0] = start
+	extent := slices.Clone(dims)
+	extent[0] = count
+
+	memspace, err := hdf5.CreateSimpleDataspace(extent, nil)
+	if err != nil {
+		return empty, err
+	}
+	defer memspace.Close()
+
+	if err := filespace.SelectHyperslab(offset, nil, extent, nil); err != nil {
+		return empty, err
+	}
+	return read(memspace, filespace)
+}
+
 func loadHdf5Categories(file *hdf5.File, name string) []int {
 	dataset, err := file.OpenDataset(name)
 	if err != nil {
@@ -756,8 +920,8 @@ func loadHdf5Categories(file *hdf5.File, name string) []int {
 	return chunkData
 }
 
-// Read an entire dataset from an hdf5 file at once (neighbours)
-func loadHdf5Neighbors(file *hdf5.File, name string) [][]int {
+// Read the neighbours of the selected test rows, all of them if rows is nil
+func loadHdf5Neighbors(file *hdf5.File, name string, selected []int) [][]int {
 	dataset, err := file.OpenDataset(name)
 	if err != nil {
 		log.Fatalf("Error opening neighbors dataset: %v", err)
@@ -775,166 +939,249 @@ func loadHdf5Neighbors(file *hdf5.File, name string) [][]int {
 
 	byteSize := getHDF5ByteSize(dataset)
 
-	chunkData := make([][]int, rows)
-
-	if byteSize == 4 {
-		chunkData1D := make([]int32, rows*dimensions)
-		dataset.Read(&chunkData1D)
-		for i := range chunkData {
-			chunkData[i] = make([]int, dimensions)
-			for j := uint(0); j < dimensions; j++ {
-				chunkData[i][j] = int(chunkData1D[uint(i)*dimensions+j])
+	chunkData, err := readRowRuns(selected, int(rows), func(start, count int) ([][]int, error) {
+		return readHdf5Rows(dataset, uint(start), uint(count), func(memspace, filespace *hdf5.Dataspace) ([][]int, error) {
+			if byteSize == 4 {
+				return readHdf5IntRows[int32](dataset, memspace, filespace, uint(count), dimensions)
 			}
-		}
-	} else if byteSize == 8 {
-		chunkData1D := make([]int, rows*dimensions)
-		dataset.Read(&chunkData1D)
-		for i := range chunkData {
-			chunkData[i] = chunkData1D[i*int(dimensions) : (i+1)*int(dimensions)]
-		}
+			return readHdf5IntRows[int64](dataset, memspace, filespace, uint(count), dimensions)
+		})
+	})
+	if err != nil {
+		log.Fatalf("Error reading %s dataset: %v", name, err)
 	}
 
 	return chunkData
 }
 
-func calculateHdf5TrainExtent(file *hdf5.File, cfg *Config) (uint, uint) {
-	dataset, err := file.OpenDataset("train")
-	if err != nil {
-		log.Fatalf("Error opening dataset: %v", err)
+// Read the neighbor distances of the selected test rows, nil if the file has
+// none. ann-benchmarks.com stores euclidean instead of squared euclidean
+// distances, these are converted to the metric of Weaviate.
+func loadHdf5Distances(file *hdf5.File, name string, cfg *Config, rows []int) [][]float32 {
+	if !file.LinkExists(name) {
+		return nil
+	}
+
+	metric := hdf5DistanceMetric(file)
+	switch {
+	case metric == "", metric == cfg.DistanceMetric:
+	case metric == "angular" && cfg.DistanceMetric == "cosine":
+	case metric == "euclidean" && cfg.DistanceMetric == "l2-squared":
+	default:
+		log.Warnf("The %s dataset is %s, not %s, recall is computed from ids only", name, metric, cfg.DistanceMetric)
+		return nil
+	}
+
+	distanceCfg := *cfg
+	distanceCfg.MultiVectorDimensions = 0
+	distanceCfg.BFloat16 = false
+	distances := loadHdf5Float32(file, name, &distanceCfg, rows)
+
+	if metric == "euclidean" {
+		for _, row := range distances {
+			for j, d := range row {
+				row[j] = d * d
+			}
+		}
 	}
-	defer dataset.Close()
-	dataspace := dataset.Space()
-	extent, _, _ := dataspace.SimpleExtentDims()
-	dimensions := extent[1]
-	rows := extent[0]
-	return rows, dimensions
+	return distances
 }
 
-func loadHdf5Train(file *hdf5.File, cfg *Config, offset uint, maxRows uint, updatePercent float32) uint {
-	dataset, err := file.OpenDataset("train")
+// The metric of an ann-benchmarks.com file from the distance attribute of
+// its root group, empty if it has none, e.g. for files of ground-truth
+func hdf5DistanceMetric(file *hdf5.File) string {
+	root, err := file.OpenGroup("/")
 	if err != nil {
-		log.Fatalf("Error opening dataset: %v", err)
+		return ""
 	}
-	defer dataset.Close()
-	dataspace := dataset.Space()
-	extent, _, _ := dataspace.SimpleExtentDims()
-	var dimensions uint
+	defer root.Close()
 
-	if cfg.MultiVectorDimensions == 0 {
-		dimensions = extent[1]
-	} else {
-		dimensions = uint(cfg.MultiVectorDimensions)
+	attribute, err := root.OpenAttribute("distance")
+	if err != nil {
+		return ""
+	}
+	defer attribute.Close()
+
+	var metric string
+	if err := attribute.Read(&metric, hdf5.T_GO_STRING); err != nil {
+		return ""
+	}
+	return metric
+}
+
+func readHdf5IntRows[D int32 | int64](dataset *hdf5.Dataset, memspace, filespace *hdf5.Dataspace,
+	rows, dimensions uint,
+) ([][]int, error) {
+	chunkData1D := make([]D, rows*dimensions)
+	if err := dataset.ReadSubset(&chunkData1D, memspace, filespace); err != nil {
+		return nil, err
 	}
 
+	chunkData := make([][]int, rows)
+	for i := range chunkData {
+		chunkData[i] = make([]int, dimensions)
+		for j := uint(0); j < dimensions; j++ {
+			chunkData[i][j] = int(chunkData1D[uint(i)*dimensions+j])
+		}
+	}
+	return chunkData, nil
+}
+
+func loadTrain(source datasetSource, cfg *Config, client *weaviate.Client, offset uint, maxRows uint, updatePercent float32, stats *importStats) uint {
+	rows, dimensions := source.trainExtent(cfg)
+
 	filters := []int{}
 	if cfg.Filter {
-		filters = loadHdf5Categories(file, "train_categories")
+		filters = source.categories("train_categories")
+	}
+
+	if maxRows != 0 && maxRows < rows {
+		rows = maxRows
+	}
+	if offset < rows {
+		rows -= offset
 	}
 
-	chunks := make(chan Batch, 10)
+	setPhase("import", 0)
+	progress := startImportProgress(cfg, client, int64(rows))
+	defer progress.Stop()
+
+	chunks := make(chan Batch, max(10, cfg.ImportParallel))
 
 	go func() {
-		if cfg.MultiVectorDimensions > 0 {
-			loadHdf5StreamingColbert(dataset, chunks, cfg, offset, maxRows, filters)
-		} else {
-			loadHdf5Streaming(dataset, chunks, cfg, offset, maxRows, filters)
+		defer close(chunks)
+		if cfg.TrainPropertiesFile == "" {
+			source.streamTrain(chunks, cfg, offset, maxRows, filters)
+			return
+		}
+
+		stream := make(chan Batch, cap(chunks))
+		go func() {
+			source.streamTrain(stream, cfg, offset, maxRows, filters)
+			close(stream)
+		}()
+		if err := addTrainProperties(stream, chunks, cfg.TrainPropertiesFile); err != nil {
+			log.Fatalf("Error reading train properties: %v", err)
 		}
-		close(chunks)
 	}()
 
+	// Workers dial their own connection unless a shared pool is configured
+	pool := make([]*grpc.ClientConn, cfg.ImportConnections)
+	for i := range pool {
+		pool[i] = dialImport(cfg, stats)
+		defer pool[i].Close()
+	}
+
 	var wg sync.WaitGroup
 
-	for i := 0; i < 8; i++ {
+	for i := 0; i < cfg.ImportParallel; i++ {
 		wg.Add(1)
-		go func() {
+		go func(worker int) {
 			defer wg.Done()
 
-			// Import workers will primary use the direct gRPC client
-			// If triggering deletes before import, we need to use the normal go client
-			grpcCtx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-			httpOption := grpc.WithInsecure()
-			if cfg.HttpScheme == "https" {
-				creds := credentials.NewTLS(&tls.Config{
-					InsecureSkipVerify: true,
-				})
-				httpOption = grpc.WithTransportCredentials(creds)
-			}
-			defer cancel()
-			opts := []retry.CallOption{
-				retry.WithBackoff(retry.BackoffExponential(100 * time.Millisecond)),
-			}
-			grpcConn, err := grpc.DialContext(grpcCtx, cfg.Origin, httpOption, grpc.WithUnaryInterceptor(retry.UnaryClientInterceptor(opts...)))
-			if err != nil {
-				log.Fatalf("Did not connect: %v", err)
+			// Import workers use the direct gRPC client, the go client is only
+			// used to trigger deletes before an update
+			var grpcConn *grpc.ClientConn
+			if len(pool) > 0 {
+				grpcConn = pool[worker%len(pool)]
+			} else {
+				grpcConn = dialImport(cfg, stats)
+				defer grpcConn.Close()
 			}
-			defer grpcConn.Close()
 			grpcClient := weaviategrpc.NewWeaviateClient(grpcConn)
-			weaviateClient := createClient(cfg)
 
 			for chunk := range chunks {
 				if updatePercent > 0 {
 					if rand.Float32() < updatePercent {
-						deleteChunk(&chunk, weaviateClient, cfg)
-						writeChunk(&chunk, &grpcClient, cfg)
+						deleteChunk(&chunk, client, cfg)
+						writeChunk(&chunk, &grpcClient, cfg, stats)
 					}
 				} else {
-					writeChunk(&chunk, &grpcClient, cfg)
+					writeChunk(&chunk, &grpcClient, cfg, stats)
 				}
+				progress.add(chunk.len())
 			}
-		}()
+		}(i)
 	}
 
 	wg.Wait()
 	return dimensions
 }
 
-// Load an hdf5 file in the format of ann-benchmarks.com
-// returns total time duration for load
-func loadANNBenchmarksFile(file *hdf5.File, cfg *Config, client *weaviate.Client, maxRows uint) time.Duration {
+// Dial a gRPC connection for import batches, with retries and the attempts
+// counted in stats
+func dialImport(cfg *Config, stats *importStats) *grpc.ClientConn {
+	grpcCtx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	httpOption := grpc.WithInsecure()
+	if cfg.HttpScheme == "https" {
+		creds := credentials.NewTLS(&tls.Config{
+			InsecureSkipVerify: true,
+		})
+		httpOption = grpc.WithTransportCredentials(creds)
+	}
+	opts := []retry.CallOption{
+		retry.WithBackoff(retry.BackoffExponential(100 * time.Millisecond)),
+	}
+	grpcConn, err := grpc.DialContext(grpcCtx, cfg.Origin, httpOption,
+		grpc.WithChainUnaryInterceptor(retry.UnaryClientInterceptor(opts...), stats.countAttempts()))
+	if err != nil {
+		log.Fatalf("Did not connect: %v", err)
+	}
+	return grpcConn
+}
+
+// Load the training vectors of a dataset in the format of ann-benchmarks.com
+// returns total time duration for load and the import statistics
+func loadANNBenchmarksFile(source datasetSource, cfg *Config, client *weaviate.Client, maxRows uint) (time.Duration, *importStats) {
 	addTenantIfNeeded(cfg, client)
 	startTime := time.Now()
+	stats := newImportStats()
 
 	if cfg.PQ == "enabled" {
-		dimensions := loadHdf5Train(file, cfg, 0, uint(cfg.TrainingLimit), 0)
+		dimensions := loadTrain(source, cfg, client, 0, uint(cfg.TrainingLimit), 0, stats)
 		log.Printf("Pausing to enable PQ.")
 		enableCompression(cfg, client, dimensions, CompressionTypePQ)
-		loadHdf5Train(file, cfg, uint(cfg.TrainingLimit), 0, 0)
+		loadTrain(source, cfg, client, uint(cfg.TrainingLimit), 0, 0, stats)
 
 	} else if cfg.SQ == "enabled" {
-		dimensions := loadHdf5Train(file, cfg, 0, uint(cfg.TrainingLimit), 0)
+		dimensions := loadTrain(source, cfg, client, 0, uint(cfg.TrainingLimit), 0, stats)
 		log.Printf("Pausing to enable SQ.")
 		enableCompression(cfg, client, dimensions, CompressionTypeSQ)
-		loadHdf5Train(file, cfg, uint(cfg.TrainingLimit), 0, 0)
+		loadTrain(source, cfg, client, uint(cfg.TrainingLimit), 0, 0, stats)
 
 	} else if cfg.LASQ == "enabled" {
-		dimensions := loadHdf5Train(file, cfg, 0, uint(cfg.TrainingLimit), 0)
+		dimensions := loadTrain(source, cfg, client, 0, uint(cfg.TrainingLimit), 0, stats)
 		log.Printf("Pausing to enable LASQ.")
 		enableCompression(cfg, client, dimensions, CompressionTypeLASQ)
-		loadHdf5Train(file, cfg, uint(cfg.TrainingLimit), 0, 0)
+		loadTrain(source, cfg, client, uint(cfg.TrainingLimit), 0, 0, stats)
 
 	} else {
-		loadHdf5Train(file, cfg, 0, maxRows, 0)
+		loadTrain(source, cfg, client, 0, maxRows, 0, stats)
 	}
 	endTime := time.Now()
 	log.WithFields(log.Fields{"duration": endTime.Sub(startTime)}).Printf("Total load time\n")
+	logImportResults(stats.results())
 	if !cfg.SkipAsyncReady {
 		endTime = waitReady(cfg, client, startTime, 4*time.Hour, 1000)
 	}
-	return endTime.Sub(startTime)
+	return endTime.Sub(startTime), stats
 }
 
 // Load a dataset multiple time with different tenants
-func loadHdf5MultiTenant(file *hdf5.File, cfg *Config, client *weaviate.Client) time.Duration {
+func loadMultiTenant(source datasetSource, cfg *Config, client *weaviate.Client) (time.Duration, *importStats) {
 	startTime := time.Now()
 
+	stats := make([]*importStats, cfg.NumTenants)
 	for i := 0; i < cfg.NumTenants; i++ {
 		cfg.Tenant = fmt.Sprintf("%d", i)
-		loadANNBenchmarksFile(file, cfg, client, 0)
+		_, stats[i] = loadANNBenchmarksFile(source, cfg, client, 0)
 	}
 
 	endTime := time.Now()
 	log.WithFields(log.Fields{"duration": endTime.Sub(startTime)}).Printf("Multi-tenant load time\n")
-	return endTime.Sub(startTime)
+	return endTime.Sub(startTime), mergeImportStats(stats)
 }
 
 func parseEfValues(s string) ([]int, error) {
@@ -950,8 +1197,90 @@ func parseEfValues(s string) ([]int, error) {
 	return nums, nil
 }
 
-func runQueries(cfg *Config, importTime time.Duration, testData [][]float32, neighbors [][]int, filters []int) {
-	runID := strconv.FormatInt(time.Now().Unix(), 10)
+// The queries of a benchmark and their ground truth, rows are aligned
+type testSet struct {
+	vectors   [][]float32
+	neighbors [][]int
+	// Neighbor distances, nil if the dataset has none
+	distances [][]float32
+	// Filters of the queries, nil without --filter or --filterSpec
+	filters []*weaviategrpc.Filters
+	// Text of hybrid queries, nil for vector search
+	texts []string
+	// Graded relevance of train rows per query, nil without --qrels
+	qrels []map[int]int
+}
+
+// Read the test vectors, their neighbors, neighbor distances, categories and
+// query texts, only the rows selected by --maxQueries and --sampleQueries.
+// The rows are picked once from the test count so every file pairs the same
+// queries.
+func loadQueries(source datasetSource, cfg *Config) *testSet {
+	rows := queryRows(cfg, source.testCount(cfg))
+	test := &testSet{vectors: source.testVectors(cfg, rows)}
+
+	test.neighbors = source.neighbors(cfg, rows)
+	if len(test.neighbors) < len(test.vectors) {
+		log.Fatalf("Found neighbors for %d of %d test vectors", len(test.neighbors), len(test.vectors))
+	}
+
+	test.distances = source.distances(cfg, rows)
+	if test.distances != nil && len(test.distances) < len(test.vectors) {
+		log.Warnf("Found distances for %d of %d test vectors, recall is computed from ids only",
+			len(test.distances), len(test.vectors))
+		test.distances = nil
+	}
+
+	test.filters = loadTestFilters(source, cfg, rows, len(test.vectors))
+
+	if cfg.QueryTextsFile != "" {
+		texts, err := readLines(cfg.QueryTextsFile)
+		if err != nil {
+			log.Fatalf("Error reading query texts: %v", err)
+		}
+		if err := checkQueryRows(rows, len(texts)); err != nil {
+			log.Fatalf("Error reading query texts: %v", err)
+		}
+		test.texts = pickRows(texts, rows)
+		if len(test.texts) < len(test.vectors) {
+			log.Fatalf("Found query texts for %d of %d test vectors", len(test.texts), len(test.vectors))
+		}
+	}
+
+	if cfg.QrelsFile != "" {
+		qrels, err := readRowQrels(cfg.QrelsFile, len(test.vectors))
+		if err != nil {
+			log.Fatalf("Error reading qrels: %v", err)
+		}
+		test.qrels = qrels
+	}
+
+	log.WithFields(log.Fields{
+		"queries": len(test.vectors), "sampled": cfg.SampleQueries, "distances": test.distances != nil,
+	}).Info("Loaded test vectors")
+	return test
+}
+
+// The lines of a text file without their line endings
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+func runQueries(cfg *Config, importTime time.Duration, importResults *ImportResults, test *testSet) {
+	queryStart := time.Now()
+	runID := strconv.FormatInt(queryStart.Unix(), 10)
 
 	efCandidates, err := parseEfValues(cfg.EfArray)
 	if err != nil {
@@ -971,67 +1300,129 @@ func runQueries(cfg *Config, importTime time.Duration, testData [][]float32, nei
 	client := createClient(cfg)
 
 	var benchmarkResultsMap []map[string]interface{}
+	var timeline []TimelineRecord
 	for _, ef := range efCandidates {
 		updateEf(ef, cfg, client)
+		setPhase("query", ef)
 
 		var result Results
 
 		if cfg.QueryDuration > 0 {
-			result = benchmarkANNDuration(*cfg, testData, neighbors, filters)
+			result = benchmarkANNDuration(*cfg, test)
 		} else {
-			result = benchmarkANN(*cfg, testData, neighbors, filters)
+			result = benchmarkANN(*cfg, test)
 		}
 
-		log.WithFields(log.Fields{
-			"mean": result.Mean, "qps": result.QueriesPerSecond, "recall": result.Recall,
+		fields := log.Fields{
+			"mean": result.Mean, "qps": result.QueriesPerSecond,
 			"parallel": cfg.Parallel, "limit": cfg.Limit,
-			"api": cfg.API, "ef": ef, "count": result.Total, "failed": result.Failed,
-		}).Info("Benchmark result")
-
-		dataset := filepath.Base(cfg.BenchmarkFile)
-
-		var resultMap map[string]interface{}
-
-		benchResult := ResultsJSONBenchmark{
-			Api:              cfg.API,
-			Ef:               ef,
-			EfConstruction:   cfg.EfConstruction,
-			MaxConnections:   cfg.MaxConnections,
-			Mean:             result.Mean.Seconds(),
-			P99Latency:       result.Percentiles[len(result.Percentiles)-1].Seconds(),
-			QueriesPerSecond: result.QueriesPerSecond,
-			Shards:           cfg.Shards,
-			Parallelization:  cfg.Parallel,
-			Limit:            cfg.Limit,
-			ImportTime:       importTime.Seconds(),
-			RunID:            runID,
-			Dataset:          dataset,
-			Recall:           result.Recall,
-			HeapAllocBytes:   memstats.HeapAllocBytes,
-			HeapInuseBytes:   memstats.HeapInuseBytes,
-			HeapSysBytes:     memstats.HeapSysBytes,
-			Timestamp:        time.Now().Format(time.RFC3339),
-		}
-
-		jsonData, err := json.Marshal(benchResult)
-		if err != nil {
-			log.Fatalf("Error converting result to json")
+			"api": cfg.API, "ef": ef, "count": result.Total, "failed": result.Failed, "queryType": cfg.QueryType,
 		}
-
-		if err := json.Unmarshal(jsonData, &resultMap); err != nil {
-			log.Fatalf("Error converting json to map")
+		if result.RecallCount > 0 {
+			fields["recall"] = result.Recall
+		}
+		if result.DistanceErrorCount > 0 {
+			fields["distanceError"] = result.DistanceError
+		}
+		if result.ShortResults > 0 {
+			fields["shortResults"] = result.ShortResults
+		}
+		if result.QrelsCount > 0 {
+			fields["ndcg"] = result.Quality.NDCG
+			fields["mrr"] = result.Quality.MRR
+			fields["map"] = result.Quality.MAP
+			fields["precision"] = result.Quality.Precision
 		}
+		log.WithFields(fields).Info("Benchmark result")
 
-		if cfg.LabelMap != nil {
-			for key, value := range cfg.LabelMap {
-				resultMap[key] = value
-			}
+		benchResult := newResultsJSONBenchmark(cfg, ef, result, importTime, runID, memstats)
+		benchResult.Import = importResults
+		benchmarkResultsMap = append(benchmarkResultsMap, benchResult.toMap(cfg))
+
+		for _, record := range result.Timeline {
+			record.Ef = ef
+			timeline = append(timeline, record)
 		}
+	}
+
+	writeBenchmarkResults(runID, benchmarkResultsMap)
+	writeTimeline(cfg, runID, queryStart, timeline)
+}
+
+func newResultsJSONBenchmark(cfg *Config, ef int, result Results, importTime time.Duration, runID string, memstats *Memstats) ResultsJSONBenchmark {
+	var recall, distanceError *float64
+	if result.RecallCount > 0 {
+		recall = &result.Recall
+	}
+	if result.DistanceErrorCount > 0 {
+		distanceError = &result.DistanceError
+	}
+	benchResult := ResultsJSONBenchmark{
+		Api:              cfg.API,
+		Ef:               ef,
+		EfConstruction:   cfg.EfConstruction,
+		MaxConnections:   cfg.MaxConnections,
+		Mean:             result.Mean.Seconds(),
+		P99Latency:       result.Percentile(99).Seconds(),
+		P999Latency:      result.Percentile(99.9).Seconds(),
+		QueriesPerSecond: result.QueriesPerSecond,
+		Shards:           cfg.Shards,
+		Parallelization:  cfg.Parallel,
+		Limit:            cfg.Limit,
+		ImportTime:       importTime.Seconds(),
+		RunID:            runID,
+		Dataset:          filepath.Base(cfg.BenchmarkFile),
+		Recall:           recall,
+		DistanceError:    distanceError,
+		HeapAllocBytes:   memstats.HeapAllocBytes,
+		HeapInuseBytes:   memstats.HeapInuseBytes,
+		HeapSysBytes:     memstats.HeapSysBytes,
+		Timestamp:        time.Now().Format(time.RFC3339),
+		TargetQPS:        cfg.TargetQPS,
+		Failed:           result.Failed,
+		ErrorRate:        result.ErrorRate,
+		BudgetExceeded:   result.BudgetExceeded,
+		Errors:           result.Errors,
+		LatencyHistogram: histogramBuckets(result.Histogram),
+		QueryType:        cfg.QueryType,
+	}
+	if result.QrelsCount > 0 {
+		benchResult.NDCG = &result.Quality.NDCG
+		benchResult.MRR = &result.Quality.MRR
+		benchResult.MAP = &result.Quality.MAP
+		benchResult.Precision = &result.Quality.Precision
+	}
+	if cfg.QueryType == "hybrid" {
+		benchResult.Alpha = cfg.Alpha
+		benchResult.FusionType = cfg.FusionType
+	}
+	return benchResult
+}
 
-		benchmarkResultsMap = append(benchmarkResultsMap, resultMap)
+// Convert a result to a generic map so labels and mode specific keys can be added
+func (r ResultsJSONBenchmark) toMap(cfg *Config) map[string]interface{} {
+	var resultMap map[string]interface{}
 
+	jsonData, err := json.Marshal(r)
+	if err != nil {
+		log.Fatalf("Error converting result to json")
+	}
+
+	if err := json.Unmarshal(jsonData, &resultMap); err != nil {
+		log.Fatalf("Error converting json to map")
 	}
 
+	if cfg.LabelMap != nil {
+		for key, value := range cfg.LabelMap {
+			resultMap[key] = value
+		}
+	}
+
+	return resultMap
+}
+
+// Write the results of a run to ./results/<runID>.json
+func writeBenchmarkResults(runID string, benchmarkResultsMap []map[string]interface{}) {
 	data, err := json.MarshalIndent(benchmarkResultsMap, "", "    ")
 	if err != nil {
 		log.Fatalf("Error marshaling benchmark results: %v", err)
@@ -1048,7 +1439,7 @@ func runQueries(cfg *Config, importTime time.Duration, testData [][]float32, nei
 var annBenchmarkCommand = &cobra.Command{
 	Use:   "ann-benchmark",
 	Short: "Benchmark ANN Benchmark style datasets",
-	Long:  `Run a gRPC benchmark on an hdf5 file in the format of ann-benchmarks.com`,
+	Long:  `Run a gRPC benchmark on an hdf5 file in the format of ann-benchmarks.com, its NumPy equivalent (.npz or a directory of .npy files) or a TEXMEX (.fvecs/.bvecs/.ivecs), big-ann-benchmarks (.fbin/.u8bin/.i8bin/.ibin) or Parquet dataset`,
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := globalConfig
 		cfg.Mode = "ann-benchmark"
@@ -1059,15 +1450,13 @@ var annBenchmarkCommand = &cobra.Command{
 
 		cfg.parseLabels()
 
-		file, err := hdf5.OpenFile(cfg.BenchmarkFile, hdf5.F_ACC_RDONLY)
-		if err != nil {
-			log.Fatalf("Error opening file: %v\n", err)
-		}
-		defer file.Close()
+		source := openDatasetSource(&cfg)
+		defer source.Close()
 
 		client := createClient(&cfg)
 
 		importTime := 0 * time.Second
+		var importResults *ImportResults
 
 		if !cfg.QueryOnly {
 
@@ -1080,11 +1469,13 @@ var annBenchmarkCommand = &cobra.Command{
 				"distance": cfg.DistanceMetric, "dataset": cfg.BenchmarkFile,
 			}).Info("Starting import")
 
+			var stats *importStats
 			if cfg.NumTenants > 0 {
-				importTime = loadHdf5MultiTenant(file, &cfg, client)
+				importTime, stats = loadMultiTenant(source, &cfg, client)
 			} else {
-				importTime = loadANNBenchmarksFile(file, &cfg, client, 0)
+				importTime, stats = loadANNBenchmarksFile(source, &cfg, client, 0)
 			}
+			importResults = stats.results()
 
 			sleepDuration := time.Duration(cfg.QueryDelaySeconds) * time.Second
 			log.Printf("Waiting for %s to allow for compaction etc\n", sleepDuration)
@@ -1100,24 +1491,13 @@ var annBenchmarkCommand = &cobra.Command{
 			return
 		}
 
-		neighbors := loadHdf5Neighbors(file, "neighbors")
-		var testData [][]float32
-		if cfg.MultiVectorDimensions > 0 {
-			testData = loadHdf5Colbert(file, "test", cfg.MultiVectorDimensions)
-		} else {
-			testData = loadHdf5Float32(file, "test", &cfg)
-		}
-
-		testFilters := make([]int, 0)
-		if cfg.Filter {
-			testFilters = loadHdf5Categories(file, "test_categories")
-		}
+		test := loadQueries(source, &cfg)
 
-		runQueries(&cfg, importTime, testData, neighbors, testFilters)
+		runQueries(&cfg, importTime, importResults, test)
 
 		if cfg.performUpdates() {
 
-			totalRowCount, _ := calculateHdf5TrainExtent(file, &cfg)
+			totalRowCount, _ := source.trainExtent(&cfg)
 			updateRowCount := uint(math.Floor(float64(totalRowCount) * cfg.UpdatePercentage))
 
 			log.Printf("Performing %d update iterations\n", cfg.UpdateIterations)
@@ -1125,13 +1505,15 @@ var annBenchmarkCommand = &cobra.Command{
 			for i := 0; i < cfg.UpdateIterations; i++ {
 
 				startTime := time.Now()
+				stats := newImportStats()
 
 				if cfg.UpdateRandomized {
-					loadHdf5Train(file, &cfg, 0, 0, float32(cfg.UpdatePercentage))
+					loadTrain(source, &cfg, client, 0, 0, float32(cfg.UpdatePercentage), stats)
 				} else {
 					deleteUuidRange(&cfg, client, 0, int(updateRowCount))
-					loadHdf5Train(file, &cfg, 0, updateRowCount, 0)
+					loadTrain(source, &cfg, client, 0, updateRowCount, 0, stats)
 				}
+				logImportResults(stats.results())
 
 				log.WithFields(log.Fields{"duration": time.Since(startTime)}).Printf("Total delete and update time\n")
 
@@ -1146,7 +1528,7 @@ var annBenchmarkCommand = &cobra.Command{
 					waitReady(&cfg, client, startTime, 30*time.Minute, 1000)
 				}
 
-				runQueries(&cfg, importTime, testData, neighbors, testFilters)
+				runQueries(&cfg, importTime, importResults, test)
 
 			}
 
@@ -1156,13 +1538,17 @@ var annBenchmarkCommand = &cobra.Command{
 
 func initAnnBenchmark() {
 	rootCmd.AddCommand(annBenchmarkCommand)
+	addQueryLoadFlags(annBenchmarkCommand)
+	addDatasetFlags(annBenchmarkCommand)
 
 	numCPU := runtime.NumCPU()
 
 	annBenchmarkCommand.PersistentFlags().StringVar(&globalConfig.Labels,
 		"labels", "", "Labels of format key1=value1,key2=value2,...")
-	annBenchmarkCommand.PersistentFlags().StringVarP(&globalConfig.BenchmarkFile,
-		"vectors", "v", "", "Path to the hdf5 file containing the vectors")
+	annBenchmarkCommand.PersistentFlags().StringVar(&globalConfig.NeighborsFile,
+		"neighbors", "", "Path to the ground truth: .ivecs, .ibin, .parquet (neighbors column) or an hdf5 file with a neighbors dataset (TEXMEX default <name>_groundtruth.ivecs next to <name>_base)")
+	annBenchmarkCommand.PersistentFlags().StringSliceVar(&globalConfig.PropertyColumns,
+		"propertyColumns", nil, "Columns of a Parquet dataset to import as properties, e.g. brand,price")
 	annBenchmarkCommand.PersistentFlags().StringVarP(&globalConfig.ClassName,
 		"className", "c", "Vector", "Class name for testing")
 	annBenchmarkCommand.PersistentFlags().StringVar(&globalConfig.NamedVector,
@@ -1213,8 +1599,16 @@ func initAnnBenchmark() {
 		"shards", 1, "Set number of Weaviate shards")
 	annBenchmarkCommand.PersistentFlags().IntVarP(&globalConfig.BatchSize,
 		"batchSize", "b", 1000, "Batch size for insert operations")
+	annBenchmarkCommand.PersistentFlags().IntVar(&globalConfig.ImportParallel,
+		"importParallel", 8, "Number of parallel workers sending import batches")
+	annBenchmarkCommand.PersistentFlags().IntVar(&globalConfig.ImportConnections,
+		"importConnections", 0, "Share this many gRPC connections between the import workers (default 0, one connection per worker)")
 	annBenchmarkCommand.PersistentFlags().IntVarP(&globalConfig.Parallel,
 		"parallel", "p", numCPU, "Set the number of parallel threads which send queries")
+	annBenchmarkCommand.PersistentFlags().IntVar(&globalConfig.TimelineInterval,
+		"timelineInterval", 1, "Interval in seconds of the QPS and latency timeline written next to the results (0 to disable)")
+	annBenchmarkCommand.PersistentFlags().StringVar(&globalConfig.TimelineFormat,
+		"timelineFormat", "jsonl", "Format of the timeline file (jsonl or csv)")
 	annBenchmarkCommand.PersistentFlags().BoolVar(&globalConfig.ExistingSchema,
 		"existingSchema", false, "Leave the schema as-is (default false)")
 	annBenchmarkCommand.PersistentFlags().IntVar(&globalConfig.NumTenants,
@@ -1251,6 +1645,32 @@ func initAnnBenchmark() {
 		"dynamicThreshold", 10_000, "Threshold to trigger the update in the dynamic index (default 10 000)")
 	annBenchmarkCommand.PersistentFlags().BoolVar(&globalConfig.Filter,
 		"filter", false, "Whether to use filtering for the dataset (default false)")
+	annBenchmarkCommand.PersistentFlags().StringVar(&globalConfig.FilterSpec,
+		"filterSpec", "", "JSON file with a where filter in the REST API format, placeholders like \"{{price}}\" are filled with the values of each test row")
+	annBenchmarkCommand.PersistentFlags().StringVar(&globalConfig.TestPropertiesFile,
+		"testProperties", "", "JSON lines file with the filter values of every test vector, e.g. {\"price\": 10}")
+	annBenchmarkCommand.PersistentFlags().StringVar(&globalConfig.TrainPropertiesFile,
+		"trainProperties", "", "JSON lines file with the properties imported with every train vector")
+	annBenchmarkCommand.PersistentFlags().StringVar(&globalConfig.QueryType,
+		"queryType", "nearVector", "Type of the test queries (nearVector or hybrid)")
+	annBenchmarkCommand.PersistentFlags().StringVar(&globalConfig.QueryTextsFile,
+		"queryTexts", "", "Text file with the keyword query of every test vector, one per line, for hybrid queries")
+	annBenchmarkCommand.PersistentFlags().StringSliceVar(&globalConfig.QueryProperties,
+		"queryProperties", nil, "Properties searched by the keyword part of hybrid queries (default all text properties)")
+	annBenchmarkCommand.PersistentFlags().Float64Var(&globalConfig.Alpha,
+		"alpha", 0.75, "Weight of the vector search in hybrid queries, 0 is pure keyword and 1 pure vector search")
+	annBenchmarkCommand.PersistentFlags().StringVar(&globalConfig.FusionType,
+		"fusionType", "relativeScore", "Fusion of keyword and vector results in hybrid queries (relativeScore or ranked)")
+	annBenchmarkCommand.PersistentFlags().Float64Var(&globalConfig.RecallEpsilon,
+		"recallEpsilon", 1e-3, "Results at most this much farther than the k-th neighbor count for recall, if the dataset has distances")
+	annBenchmarkCommand.PersistentFlags().StringVar(&globalConfig.QrelsFile,
+		"qrels", "", "BEIR qrels .tsv with the graded relevance of train rows (corpus-id) for test rows (query-id), enables nDCG, MRR, MAP and precision")
+	annBenchmarkCommand.PersistentFlags().IntVar(&globalConfig.MaxQueries,
+		"maxQueries", 0, "Only read and query the first n test vectors, or a random sample of n with --sampleQueries (default 0, all)")
+	annBenchmarkCommand.PersistentFlags().BoolVar(&globalConfig.SampleQueries,
+		"sampleQueries", false, "Query a random sample of --maxQueries test vectors instead of the first ones")
+	annBenchmarkCommand.PersistentFlags().Int64Var(&globalConfig.Seed,
+		"seed", 0, "Seed of the --sampleQueries sample")
 	annBenchmarkCommand.PersistentFlags().IntVar(&globalConfig.FlatSearchCutoff,
 		"flatSearchCutoff", 40000, "Flat search cut off (default 40 000)")
 	annBenchmarkCommand.PersistentFlags().StringVar(&globalConfig.FilterStrategy,
@@ -1261,27 +1681,41 @@ func initAnnBenchmark() {
 		"asyncReplicationEnabled", false, "Enable asynchronous replication (default false)")
 }
 
-func benchmarkANN(cfg Config, queries Queries, neighbors Neighbors, filters []int) Results {
-	cfg.Queries = len(queries)
+func benchmarkANN(cfg Config, test *testSet) Results {
+	cfg.Queries = len(test.vectors)
+	return benchmark(cfg, annQueryFn(&cfg, test))
+}
 
+// Returns a query function which walks over the test set, wrapping around
+// if more than len(test.vectors) queries are requested
+func annQueryFn(cfg *Config, test *testSet) func(className string) QueryWithNeighbors {
 	i := 0
-	return benchmark(cfg, func(className string) QueryWithNeighbors {
-		defer func() { i++ }()
+	return func(className string) QueryWithNeighbors {
+		defer func() { i = (i + 1) % len(test.vectors) }()
 
 		tenant := ""
 		if cfg.NumTenants > 0 {
 			tenant = fmt.Sprint(rand.Intn(cfg.NumTenants))
 		}
-		filter := -1
-		if cfg.Filter {
-			filter = filters[i]
+		var filters *weaviategrpc.Filters
+		if test.filters != nil {
+			filters = test.filters[i]
 		}
 
-		return QueryWithNeighbors{
-			Query:     nearVectorQueryGrpc(&cfg, queries[i], tenant, filter),
-			Neighbors: neighbors[i],
+		query := QueryWithNeighbors{Neighbors: test.neighbors[i]}
+		if cfg.QueryType == "hybrid" {
+			query.Query = hybridQueryGrpc(cfg, test.vectors[i], test.texts[i], tenant, filters)
+		} else {
+			query.Query = nearVectorQueryGrpc(cfg, test.vectors[i], tenant, filters)
 		}
-	})
+		if test.distances != nil {
+			query.Distances = test.distances[i]
+		}
+		if test.qrels != nil {
+			query.Qrels = test.qrels[i]
+		}
+		return query
+	}
 }
 
 type Number interface {
@@ -1307,6 +1741,23 @@ func median[T Number](data []T) float64 {
 	return median
 }
 
+// The median of every retrieval quality metric
+func medianQuality(samples []retrievalQuality) retrievalQuality {
+	metrics := make([][]float64, 4)
+	for _, q := range samples {
+		metrics[0] = append(metrics[0], q.NDCG)
+		metrics[1] = append(metrics[1], q.MRR)
+		metrics[2] = append(metrics[2], q.MAP)
+		metrics[3] = append(metrics[3], q.Precision)
+	}
+	return retrievalQuality{
+		NDCG:      median(metrics[0]),
+		MRR:       median(metrics[1]),
+		MAP:       median(metrics[2]),
+		Precision: median(metrics[3]),
+	}
+}
+
 type sampledResults struct {
 	Min              []time.Duration
 	Max              []time.Duration
@@ -1314,11 +1765,47 @@ type sampledResults struct {
 	Took             []time.Duration
 	QueriesPerSecond []float64
 	Recall           []float64
+	DistanceError    []float64
+	Quality          []retrievalQuality
 	Results          []Results
 }
 
-func benchmarkANNDuration(cfg Config, queries Queries, neighbors Neighbors, filters []int) Results {
-	cfg.Queries = len(queries)
+// Set the latency distribution and query counts of out over all samples,
+// percentiles are taken from the merged histograms of every iteration. The
+// recall, distance error and quality counts are the queries behind the
+// medians of all iterations.
+func (s sampledResults) mergeCounts(out *Results) {
+	out.Histogram = newLatencyHistogram()
+	out.Errors = map[string]int{}
+	for _, r := range s.Results {
+		if r.Histogram != nil {
+			out.Histogram.Merge(r.Histogram)
+		}
+		out.Total += r.Total
+		out.Successful += r.Successful
+		out.Failed += r.Failed
+		out.ShortResults += r.ShortResults
+		out.RecallCount += r.RecallCount
+		out.DistanceErrorCount += r.DistanceErrorCount
+		out.QrelsCount += r.QrelsCount
+		for class, count := range r.Errors {
+			out.Errors[class] += count
+		}
+		out.BudgetExceeded = out.BudgetExceeded || r.BudgetExceeded
+	}
+	if sent := out.Successful + out.Failed; sent > 0 {
+		out.ErrorRate = float64(out.Failed) / float64(sent)
+	}
+
+	out.PercentilesLabels = targetPercentiles
+	out.Percentiles = make([]time.Duration, len(targetPercentiles))
+	for i, percentile := range targetPercentiles {
+		out.Percentiles[i] = histogramPercentile(out.Histogram, percentile)
+	}
+}
+
+func benchmarkANNDuration(cfg Config, test *testSet) Results {
+	cfg.Queries = len(test.vectors)
 
 	var samples sampledResults
 
@@ -1327,13 +1814,21 @@ func benchmarkANNDuration(cfg Config, queries Queries, neighbors Neighbors, filt
 	var results Results
 
 	for time.Since(startTime) < time.Duration(cfg.QueryDuration)*time.Second {
-		results = benchmarkANN(cfg, queries, neighbors, filters)
+		results = benchmarkANN(cfg, test)
 		samples.Min = append(samples.Min, results.Min)
 		samples.Max = append(samples.Max, results.Max)
 		samples.Mean = append(samples.Mean, results.Mean)
 		samples.Took = append(samples.Took, results.Took)
 		samples.QueriesPerSecond = append(samples.QueriesPerSecond, results.QueriesPerSecond)
-		samples.Recall = append(samples.Recall, results.Recall)
+		if results.RecallCount > 0 {
+			samples.Recall = append(samples.Recall, results.Recall)
+		}
+		if results.DistanceErrorCount > 0 {
+			samples.DistanceError = append(samples.DistanceError, results.DistanceError)
+		}
+		if results.QrelsCount > 0 {
+			samples.Quality = append(samples.Quality, results.Quality)
+		}
 		samples.Results = append(samples.Results, results)
 	}
 
@@ -1344,13 +1839,22 @@ func benchmarkANNDuration(cfg Config, queries Queries, neighbors Neighbors, filt
 	medianResult.Mean = time.Duration(median(samples.Mean))
 	medianResult.Took = time.Duration(median(samples.Took))
 	medianResult.QueriesPerSecond = median(samples.QueriesPerSecond)
-	medianResult.Percentiles = results.Percentiles
-	medianResult.PercentilesLabels = results.PercentilesLabels
-	medianResult.Total = results.Total
-	medianResult.Successful = results.Successful
-	medianResult.Failed = results.Failed
+	samples.mergeCounts(&medianResult)
 	medianResult.Parallelization = cfg.Parallel
-	medianResult.Recall = median(samples.Recall)
+	if len(samples.Recall) > 0 {
+		medianResult.Recall = median(samples.Recall)
+	}
+	if len(samples.DistanceError) > 0 {
+		medianResult.DistanceError = median(samples.DistanceError)
+	}
+	if len(samples.Quality) > 0 {
+		medianResult.Quality = medianQuality(samples.Quality)
+	}
+
+	// The timeline covers the whole duration, not just the last run
+	for _, sample := range samples.Results {
+		medianResult.Timeline = append(medianResult.Timeline, sample.Timeline...)
+	}
 
 	return medianResult
 }
@@ -13,10 +13,12 @@ import (
 	"net"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
 	log "github.com/sirupsen/logrus"
 
 	wv1 "github.com/weaviate/weaviate/grpc/generated/protocol/v1"
@@ -29,122 +31,305 @@ import (
 type QueryWithNeighbors struct {
 	Query     []byte
 	Neighbors []int
+	// Ground truth distances of the neighbors, nil if the dataset has none
+	Distances []float32
+	// Graded relevance of the object rows judged for a keyword query, scored
+	// instead of Neighbors if set
+	Qrels map[int]int
 }
 
-func processQueueHttp(queue []QueryWithNeighbors, cfg *Config, c *http.Client, m *sync.Mutex, times *[]time.Duration) {
+func processQueueHttp(queue []QueryWithNeighbors, cfg *Config, c *http.Client, latencies *latencyRecorder) {
 	for _, query := range queue {
-		r := bytes.NewReader(query.Query)
-		before := time.Now()
-		var url string
-		origin := fmt.Sprintf("%s://%s", cfg.HttpScheme, cfg.HttpOrigin)
-		if cfg.API == "graphql" {
-			url = origin + "/v1/graphql"
-		} else if cfg.API == "rest" {
-			url = fmt.Sprintf("%s/v1/objects/%s/_search", origin, cfg.ClassName)
-		}
-		req, err := http.NewRequest("POST", url, r)
-		if err != nil {
-			fmt.Printf("ERROR: %v\n", err)
-			continue
+		if took, err := queryHttp(query, cfg, c, time.Now()); err != nil {
+			latencies.recordError(err)
+		} else {
+			latencies.record(took)
 		}
+	}
+}
+
+// Send a single query over http, the latency is measured from start
+func queryHttp(query QueryWithNeighbors, cfg *Config, c *http.Client, start time.Time) (time.Duration, error) {
+	r := bytes.NewReader(query.Query)
+	var url string
+	origin := fmt.Sprintf("%s://%s", cfg.HttpScheme, cfg.HttpOrigin)
+	if cfg.API == "graphql" {
+		url = origin + "/v1/graphql"
+	} else if cfg.API == "rest" {
+		url = fmt.Sprintf("%s/v1/objects/%s/_search", origin, cfg.ClassName)
+	}
+	req, err := http.NewRequest("POST", url, r)
+	if err != nil {
+		return 0, failedHttpQuery(0, fmt.Errorf("failed to create http request: %w", err))
+	}
 
-		req.Header.Set("content-type", "application/json")
+	req.Header.Set("content-type", "application/json")
 
-		if cfg.HttpAuth != "" {
-			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cfg.HttpAuth))
-		}
+	if cfg.HttpAuth != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cfg.HttpAuth))
+	}
 
-		res, err := c.Do(req)
-		if err != nil {
-			fmt.Printf("ERROR: %v\n", err)
-			continue
-		}
-		took := time.Since(before)
-		bytes, _ := ioutil.ReadAll(res.Body)
-		res.Body.Close()
-		var result map[string]interface{}
-		if err := json.Unmarshal(bytes, &result); err != nil {
-			fmt.Printf("JSON error: %v\n", err)
+	benchmarkerMetrics.queriesInFlight.Inc()
+	res, err := c.Do(req)
+	benchmarkerMetrics.queriesInFlight.Dec()
+	if err != nil {
+		return 0, failedHttpQuery(0, err)
+	}
+	took := time.Since(start)
+	bytes, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		return 0, failedHttpQuery(res.StatusCode, fmt.Errorf("%s", bytes))
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return 0, failedHttpQuery(res.StatusCode, err)
+	}
+	if cfg.API == "graphql" {
+		if result["data"] != nil && result["errors"] == nil {
+			benchmarkerMetrics.observeQuery(took)
+			return took, nil
 		}
-		if cfg.API == "graphql" {
-			if result["data"] != nil && result["errors"] == nil {
-				m.Lock()
-				*times = append(*times, took)
-				m.Unlock()
-			} else {
-				fmt.Printf("GraphQL Error: %v\n", result)
-			}
-		} else {
-			if list, ok := result["objects"].([]interface{}); ok {
-				if len(list) > 0 {
-					m.Lock()
-					*times = append(*times, took)
-					m.Unlock()
-				} else {
-					fmt.Printf("REST Error: %v\n", result)
-				}
-			} else {
-				fmt.Printf("REST Error: %v\n", result)
-			}
+		return 0, failedHttpQuery(res.StatusCode, fmt.Errorf("graphql error: %v", result))
+	} else {
+		if list, ok := result["objects"].([]interface{}); ok && len(list) > 0 {
+			benchmarkerMetrics.observeQuery(took)
+			return took, nil
 		}
+		return 0, failedHttpQuery(res.StatusCode, fmt.Errorf("rest error: %v", result))
 	}
 }
 
-func processQueueGrpc(queue []QueryWithNeighbors, cfg *Config, grpcConn *grpc.ClientConn, m *sync.Mutex, times *[]time.Duration, recall *[]float64) {
+func failedHttpQuery(statusCode int, err error) error {
+	qe := httpQueryError(statusCode, err)
+	log.Debugf("Query failed: %v", qe)
+	benchmarkerMetrics.queryFailed(qe.class)
+	return qe
+}
+
+func processQueueGrpc(queue []QueryWithNeighbors, cfg *Config, grpcConn *grpc.ClientConn, latencies *latencyRecorder) {
 
 	grpcClient := wv1.NewWeaviateClient(grpcConn)
 
 	for _, query := range queue {
-
-		searchRequest := &wv1.SearchRequest{}
-		err := proto.Unmarshal(query.Query, searchRequest)
+		took, score, err := queryGrpc(query, cfg, grpcClient, time.Now())
 		if err != nil {
-			log.Fatalf("Failed to unmarshal grpc query: %v", err)
+			latencies.recordError(err)
+			continue
 		}
 
-		before := time.Now()
+		latencies.record(took)
+		latencies.recordScore(score)
+	}
+}
+
+// Send a single query over gRPC, the latency is measured from start
+func queryGrpc(query QueryWithNeighbors, cfg *Config, grpcClient wv1.WeaviateClient, start time.Time) (time.Duration, queryScore, error) {
+	searchRequest := &wv1.SearchRequest{}
+	err := proto.Unmarshal(query.Query, searchRequest)
+	if err != nil {
+		log.Fatalf("Failed to unmarshal grpc query: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+	if cfg.HttpAuth != "" {
+		md := metadata.Pairs(
+			"Authorization", fmt.Sprintf("Bearer %s", cfg.HttpAuth),
+		)
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
 
-		if cfg.HttpAuth != "" {
-			md := metadata.Pairs(
-				"Authorization", fmt.Sprintf("Bearer %s", cfg.HttpAuth),
-			)
-			ctx = metadata.NewOutgoingContext(ctx, md)
+	benchmarkerMetrics.queriesInFlight.Inc()
+	searchReply, err := grpcClient.Search(ctx, searchRequest)
+	benchmarkerMetrics.queriesInFlight.Dec()
+	if err != nil {
+		qe := grpcQueryError(err)
+		log.Debugf("Query failed: %v", qe)
+		benchmarkerMetrics.queryFailed(qe.class)
+		return 0, queryScore{}, qe
+	}
+	took := time.Since(start)
+	benchmarkerMetrics.observeQuery(took)
+
+	ids := make([]int, 0, len(searchReply.GetResults()))
+	var distances []float32
+	for _, result := range searchReply.GetResults() {
+		ids = append(ids, intFromUUID(result.GetMetadata().Id))
+		if result.GetMetadata().GetDistancePresent() {
+			distances = append(distances, result.GetMetadata().GetDistance())
 		}
+	}
+	if len(distances) != len(ids) {
+		distances = nil
+	}
 
-		searchReply, err := grpcClient.Search(ctx, searchRequest)
-		if err != nil {
-			log.Fatalf("Could not search with grpc: %v", err)
+	score := scoreQuery(cfg, query, ids, distances)
+	score.shortResults = len(ids) < cfg.Limit
+	log.Debugf("Query took %s, recall %f", took, score.recall)
+
+	return took, score, nil
+}
+
+// The quality of the results of a single query
+type queryScore struct {
+	// Share of the neighbors or judged relevant rows found, only set if
+	// hasRecall
+	recall    float64
+	hasRecall bool
+	// Relative error of the summed result distances, only set if hasDistances
+	distanceError float64
+	hasDistances  bool
+	// Quality against graded relevance judgments, only set if hasQrels
+	quality  retrievalQuality
+	hasQrels bool
+	// Fewer results than cfg.Limit were returned
+	shortResults bool
+}
+
+// Information retrieval metrics at k = cfg.Limit, either of a single query or
+// summed up or averaged over many
+type retrievalQuality struct {
+	NDCG      float64 `json:"ndcg"`
+	MRR       float64 `json:"mrr"`
+	MAP       float64 `json:"map"`
+	Precision float64 `json:"precision"`
+}
+
+func (q retrievalQuality) add(other retrievalQuality) retrievalQuality {
+	return retrievalQuality{
+		NDCG:      q.NDCG + other.NDCG,
+		MRR:       q.MRR + other.MRR,
+		MAP:       q.MAP + other.MAP,
+		Precision: q.Precision + other.Precision,
+	}
+}
+
+func (q retrievalQuality) scale(factor float64) retrievalQuality {
+	return retrievalQuality{
+		NDCG:      q.NDCG * factor,
+		MRR:       q.MRR * factor,
+		MAP:       q.MAP * factor,
+		Precision: q.Precision * factor,
+	}
+}
+
+// Score the results of a query against its ground truth neighbors and, if it
+// has relevance judgments, its qrels. Keyword queries without neighbors use
+// the recall against the qrels.
+func scoreQuery(cfg *Config, query QueryWithNeighbors, ids []int, distances []float32) queryScore {
+	score := scoreNeighbors(cfg, query, ids, distances)
+	if query.Qrels == nil {
+		return score
+	}
+
+	recall, quality, ok := scoreQrels(cfg, query.Qrels, ids)
+	if ok {
+		score.quality = quality
+		score.hasQrels = true
+		if len(query.Neighbors) == 0 {
+			score.recall = recall
+			score.hasRecall = true
 		}
-		took := time.Since(before)
+	}
+	return score
+}
+
+// Score the results against the ground truth neighbors. Without ground truth
+// distances recall is the share of neighbor ids found. With distances a
+// result counts if it is at most cfg.RecallEpsilon farther than the k-th
+// neighbor like in ann-benchmarks.com, so ties at the k-th distance are not
+// mis-scored. The distance error compares the sum of the
+// result distances to the sum of the neighbor distances.
+func scoreNeighbors(cfg *Config, query QueryWithNeighbors, ids []int, distances []float32) queryScore {
+	neighborLimit := min(cfg.Limit, len(query.Neighbors))
+	if neighborLimit == 0 {
+		return queryScore{}
+	}
 
-		if len(searchReply.GetResults()) != cfg.Limit {
-			fmt.Printf("Warning grpc got %d results, expected %d\n", len(searchReply.GetResults()), cfg.Limit)
+	if len(query.Distances) < neighborLimit || distances == nil {
+		return queryScore{
+			recall:    float64(len(intersection(ids, query.Neighbors[:neighborLimit]))) / float64(neighborLimit),
+			hasRecall: true,
 		}
+	}
 
-		ids := make([]int, 0, len(searchReply.GetResults()))
-		for _, result := range searchReply.GetResults() {
-			ids = append(ids, intFromUUID(result.GetMetadata().Id))
+	threshold := query.Distances[neighborLimit-1] + float32(cfg.RecallEpsilon)
+	found := 0
+	for _, d := range distances[:min(neighborLimit, len(distances))] {
+		if d <= threshold {
+			found++
 		}
+	}
+	score := queryScore{recall: float64(found) / float64(neighborLimit), hasRecall: true}
+
+	// Padded ground truth of filtered queries has no meaningful distances
+	if len(distances) >= neighborLimit && query.Neighbors[neighborLimit-1] >= 0 {
+		var resultSum, neighborSum float64
+		for i := 0; i < neighborLimit; i++ {
+			resultSum += float64(distances[i])
+			neighborSum += float64(query.Distances[i])
+		}
+		if neighborSum != 0 {
+			score.distanceError = (resultSum - neighborSum) / math.Abs(neighborSum)
+			score.hasDistances = true
+		}
+	}
+	return score
+}
 
-		neighborLimit := min(cfg.Limit, len(query.Neighbors))
-		recallQuery := float64(len(intersection(ids, query.Neighbors[:neighborLimit]))) / float64(neighborLimit)
+// Score the results of a query against graded relevance judgments with the
+// definitions of trec_eval used by BEIR: recall is the share of relevant
+// documents found in the top k, nDCG@k uses the grades as linear gains,
+// MAP@k averages the precision at every relevant result over all relevant
+// documents and P@k is the share of relevant results among k. ok is false if
+// the query has no relevant documents.
+func scoreQrels(cfg *Config, qrels map[int]int, ids []int) (float64, retrievalQuality, bool) {
+	var grades []int
+	for _, grade := range qrels {
+		if grade > 0 {
+			grades = append(grades, grade)
+		}
+	}
+	if len(grades) == 0 {
+		return 0, retrievalQuality{}, false
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(grades)))
 
-		log.Debugf("Query took %s, recall %f", took, recallQuery)
+	found := 0
+	var dcg, precisionSum, reciprocalRank float64
+	for i, id := range ids[:min(cfg.Limit, len(ids))] {
+		grade := qrels[id]
+		if grade <= 0 {
+			continue
+		}
+		found++
+		dcg += float64(grade) / math.Log2(float64(i+2))
+		precisionSum += float64(found) / float64(i+1)
+		if reciprocalRank == 0 {
+			reciprocalRank = 1 / float64(i+1)
+		}
+	}
 
-		m.Lock()
-		*times = append(*times, took)
-		*recall = append(*recall, recallQuery)
-		m.Unlock()
+	var idcg float64
+	for i, grade := range grades[:min(cfg.Limit, len(grades))] {
+		idcg += float64(grade) / math.Log2(float64(i+2))
 	}
+
+	return float64(found) / float64(len(grades)), retrievalQuality{
+		NDCG:      dcg / idcg,
+		MRR:       reciprocalRank,
+		MAP:       precisionSum / float64(len(grades)),
+		Precision: float64(found) / float64(cfg.Limit),
+	}, true
 }
 
 func benchmark(cfg Config, getQueryFn func(className string) QueryWithNeighbors) Results {
-	var times []time.Duration
-	var recall []float64
-	m := &sync.Mutex{}
+	recorders := make([]*latencyRecorder, cfg.Parallel)
+	for i := range recorders {
+		recorders[i] = newLatencyRecorder()
+	}
 
 	t := &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
@@ -178,34 +363,81 @@ func benchmark(cfg Config, getQueryFn func(className string) QueryWithNeighbors)
 	}
 	defer grpcConn.Close()
 
-	queues := make([][]QueryWithNeighbors, cfg.Parallel)
 	rand.Seed(time.Now().UnixNano())
 
-	for i := 0; i < cfg.Queries; i++ {
-		query := getQueryFn(cfg.ClassName)
-		worker := i % cfg.Parallel
-		queues[worker] = append(queues[worker], query)
+	queries := make([]QueryWithNeighbors, cfg.Queries)
+	for i := range queries {
+		queries[i] = getQueryFn(cfg.ClassName)
+	}
+
+	// The timeline also feeds the progress output, it is only part of the
+	// results if an interval is configured. Otherwise only the intervals of
+	// the progress window are kept.
+	tl := newRollingTimeline(time.Second, progressQueryWindow+1)
+	if cfg.TimelineInterval > 0 {
+		tl = newTimeline(time.Duration(cfg.TimelineInterval) * time.Second)
 	}
+	budget := newErrorBudget(&cfg)
+	for _, r := range recorders {
+		r.timeline = tl
+		r.budget = budget
+	}
+
+	progress := startQueryProgress(tl)
 
-	wg := &sync.WaitGroup{}
 	before := time.Now()
-	for _, queue := range queues {
-		wg.Add(1)
-		go func(queue []QueryWithNeighbors) {
-			defer wg.Done()
+	if cfg.TargetQPS > 0 {
+		grpcClient := wv1.NewWeaviateClient(grpcConn)
+		runOpenLoop(&cfg, queries, func(worker int, query QueryWithNeighbors, intended time.Time) {
+			latencies := recorders[worker]
 			if cfg.API == "grpc" {
-				processQueueGrpc(queue, &cfg, grpcConn, m, &times, &recall)
+				took, score, err := queryGrpc(query, &cfg, grpcClient, intended)
+				if err != nil {
+					latencies.recordError(err)
+					return
+				}
+				latencies.record(took)
+				latencies.recordScore(score)
+			} else if took, err := queryHttp(query, &cfg, httpClient, intended); err != nil {
+				latencies.recordError(err)
 			} else {
-				processQueueHttp(queue, &cfg, httpClient, m, &times)
+				latencies.record(took)
 			}
-		}(queue)
+		})
+	} else {
+		queues := make([][]QueryWithNeighbors, cfg.Parallel)
+		for i, query := range queries {
+			worker := i % cfg.Parallel
+			queues[worker] = append(queues[worker], query)
+		}
+
+		wg := &sync.WaitGroup{}
+		for i, queue := range queues {
+			wg.Add(1)
+			go func(queue []QueryWithNeighbors, latencies *latencyRecorder) {
+				defer wg.Done()
+				if cfg.API == "grpc" {
+					processQueueGrpc(queue, &cfg, grpcConn, latencies)
+				} else {
+					processQueueHttp(queue, &cfg, httpClient, latencies)
+				}
+			}(queue, recorders[i])
+		}
+		wg.Wait()
 	}
-	wg.Wait()
 
-	return analyze(cfg, times, time.Since(before), recall)
+	progress.Stop()
+	budgetExceeded := budget.finish()
+
+	result := analyze(cfg, mergeLatencyRecorders(recorders), time.Since(before))
+	result.BudgetExceeded = budgetExceeded
+	if cfg.TimelineInterval > 0 {
+		result.Timeline = tl.records(time.Now())
+	}
+	return result
 }
 
-var targetPercentiles = []int{50, 90, 95, 98, 99}
+var targetPercentiles = []float64{50, 90, 95, 98, 99, 99.9, 99.99, 99.999}
 
 type Results struct {
 	Min               time.Duration
@@ -214,64 +446,93 @@ type Results struct {
 	Took              time.Duration
 	QueriesPerSecond  float64
 	Percentiles       []time.Duration
-	PercentilesLabels []int
+	PercentilesLabels []float64
+	Histogram         *hdrhistogram.Histogram
 	Total             int
 	Successful        int
 	Failed            int
-	Parallelization   int
-	Recall            float64
+	Errors            map[string]int
+	ErrorRate         float64
+	// Set if the error rate of the run exceeded --maxErrorRate
+	BudgetExceeded bool
+	// Successful queries that returned fewer than --limit results
+	ShortResults    int
+	Parallelization int
+	// Mean recall of the queries with neighbors or relevance judgments, only
+	// set if RecallCount > 0
+	Recall      float64
+	RecallCount int
+	// Mean relative distance error, only set if DistanceErrorCount > 0
+	DistanceError      float64
+	DistanceErrorCount int
+	// Mean retrieval quality of the queries with relevance judgments, only
+	// set if QrelsCount > 0
+	Quality    retrievalQuality
+	QrelsCount int
+	TargetQPS  float64
+	Timeline   []TimelineRecord
 }
 
-func analyze(cfg Config, times []time.Duration, total time.Duration, recall []float64) Results {
-	out := Results{Min: math.MaxInt64, PercentilesLabels: targetPercentiles}
-	var sum time.Duration
-
-	for _, time := range times {
-		if time < out.Min {
-			out.Min = time
-		}
-
-		if time > out.Max {
-			out.Max = time
-		}
+func analyze(cfg Config, latencies *latencyRecorder, total time.Duration) Results {
+	out := Results{PercentilesLabels: targetPercentiles, Histogram: latencies.histogram}
 
-		out.Successful++
-		sum += time
+	out.Successful = latencies.count
+	out.Total = cfg.Queries
+	out.Failed = latencies.failed
+	out.Errors = latencies.errors
+	out.ShortResults = latencies.shortResults
+	if sent := out.Successful + out.Failed; sent > 0 {
+		out.ErrorRate = float64(out.Failed) / float64(sent)
 	}
+	out.Parallelization = cfg.Parallel
+	out.TargetQPS = cfg.TargetQPS
+	out.Took = total
+	out.QueriesPerSecond = float64(latencies.count) / float64(float64(total)/float64(time.Second))
 
-	var sumRecall float64
-	for _, r := range recall {
-		sumRecall += r
+	if latencies.count > 0 {
+		out.Min = latencies.min
+		out.Max = latencies.max
+		out.Mean = latencies.sum / time.Duration(latencies.count)
 	}
 
-	out.Total = cfg.Queries
-	out.Failed = cfg.Queries - out.Successful
-	out.Parallelization = cfg.Parallel
-	out.Mean = sum / time.Duration(len(times))
-	out.Took = total
-	out.QueriesPerSecond = float64(len(times)) / float64(float64(total)/float64(time.Second))
-	out.Recall = sumRecall / float64(len(recall))
+	if latencies.recallCount > 0 {
+		out.Recall = latencies.recallSum / float64(latencies.recallCount)
+		out.RecallCount = latencies.recallCount
+	}
 
-	sort.Slice(times, func(a, b int) bool {
-		return times[a] < times[b]
-	})
+	if latencies.distanceErrorCount > 0 {
+		out.DistanceError = latencies.distanceErrorSum / float64(latencies.distanceErrorCount)
+		out.DistanceErrorCount = latencies.distanceErrorCount
+	}
 
-	percentilePos := func(percentile int) int {
-		return int(float64(len(times)*percentile)/100) + 1
+	if latencies.qrelsCount > 0 {
+		out.Quality = latencies.qualitySum.scale(1 / float64(latencies.qrelsCount))
+		out.QrelsCount = latencies.qrelsCount
 	}
 
 	out.Percentiles = make([]time.Duration, len(targetPercentiles))
 	for i, percentile := range targetPercentiles {
-		pos := percentilePos(percentile)
-		if pos >= len(times) {
-			pos = len(times) - 1
-		}
-		out.Percentiles[i] = times[pos]
+		out.Percentiles[i] = histogramPercentile(latencies.histogram, percentile)
 	}
 
 	return out
 }
 
+// Format a percentile as a label, e.g. p99 or p99.9
+func percentileLabel(percentile float64) string {
+	return "p" + strconv.FormatFloat(percentile, 'f', -1, 64)
+}
+
+// Returns the latency at the given target percentile
+func (r Results) Percentile(percentile float64) time.Duration {
+	for i, label := range r.PercentilesLabels {
+		if label == percentile && i < len(r.Percentiles) {
+			return r.Percentiles[i]
+		}
+	}
+	return 0
+}
+
 func intersection(a, b []int) []int {
 	setA := make(map[int]bool)
 	var result []int
@@ -295,13 +556,46 @@ func (r Results) WriteTextTo(w io.Writer) (int64, error) {
 
 	for i, percentile := range targetPercentiles {
 		b.WriteString(
-			fmt.Sprintf("p%d: %s\n", percentile, r.Percentiles[i]),
+			fmt.Sprintf("%s: %s\n", percentileLabel(percentile), r.Percentiles[i]),
 		)
 	}
 
+	if r.TargetQPS > 0 {
+		b.WriteString(fmt.Sprintf("Target QPS: %f\n", r.TargetQPS))
+	}
+
+	errors := strings.Builder{}
+	classes := make([]string, 0, len(r.Errors))
+	for class := range r.Errors {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		errors.WriteString(fmt.Sprintf("  %s: %d\n", class, r.Errors[class]))
+	}
+
+	budget := ""
+	if r.BudgetExceeded {
+		budget = "Error budget exceeded\n"
+	}
+
+	short := ""
+	if r.ShortResults > 0 {
+		short = fmt.Sprintf("Short results: %d\n", r.ShortResults)
+	}
+
+	quality := ""
+	if r.RecallCount > 0 {
+		quality = fmt.Sprintf("Recall: %f\n", r.Recall)
+	}
+	if r.QrelsCount > 0 {
+		quality += fmt.Sprintf("nDCG: %f\nMRR: %f\nMAP: %f\nPrecision: %f\n",
+			r.Quality.NDCG, r.Quality.MRR, r.Quality.MAP, r.Quality.Precision)
+	}
+
 	n, err := w.Write([]byte(fmt.Sprintf(
-		"Results\nSuccessful: %d\nMin: %s\nMean: %s\n%sTook: %s\nQPS: %f\nRecall: %f\n",
-		r.Successful, r.Min, r.Mean, b.String(), r.Took, r.QueriesPerSecond, r.Recall)))
+		"Results\nSuccessful: %d\nFailed: %d\n%sError rate: %f\n%s%sMin: %s\nMean: %s\n%sTook: %s\nQPS: %f\n%s",
+		r.Successful, r.Failed, errors.String(), r.ErrorRate, budget, short, r.Min, r.Mean, b.String(), r.Took, r.QueriesPerSecond, quality)))
 	return int64(n), err
 }
 
@@ -310,19 +604,33 @@ type resultsJSON struct {
 	Latencies          map[string]int64      `json:"latencies"`
 	LatenciesFormatted map[string]string     `json:"latenciesFormatted"`
 	Throughput         resultsJSONThroughput `json:"throughput"`
+	Histogram          []histogramBucket     `json:"histogram"`
+	Quality            *resultsJSONQuality   `json:"quality,omitempty"`
+}
+
+// Retrieval quality against relevance judgments
+type resultsJSONQuality struct {
+	Recall *float64 `json:"recall,omitempty"`
+	retrievalQuality
+	Queries int `json:"queries"`
 }
 
 type resultsJSONMetadata struct {
-	Successful      int    `json:"successful"`
-	Failed          int    `json:"failed"`
-	Total           int    `json:"total"`
-	Parallelization int    `json:"parallelization"`
-	Took            int64  `json:"took"`
-	TookFormatted   string `json:"tookFormatted"`
+	Successful      int            `json:"successful"`
+	Failed          int            `json:"failed"`
+	Errors          map[string]int `json:"errors,omitempty"`
+	ErrorRate       float64        `json:"errorRate"`
+	BudgetExceeded  bool           `json:"budgetExceeded,omitempty"`
+	ShortResults    int            `json:"shortResults,omitempty"`
+	Total           int            `json:"total"`
+	Parallelization int            `json:"parallelization"`
+	Took            int64          `json:"took"`
+	TookFormatted   string         `json:"tookFormatted"`
 }
 
 type resultsJSONThroughput struct {
-	QPS float64 `json:"qps"`
+	QPS       float64 `json:"qps"`
+	TargetQPS float64 `json:"targetQps,omitempty"`
 }
 
 func (r Results) WriteJSONTo(w io.Writer) (int, error) {
@@ -331,6 +639,10 @@ func (r Results) WriteJSONTo(w io.Writer) (int, error) {
 			Successful:      r.Successful,
 			Total:           r.Total,
 			Failed:          r.Failed,
+			Errors:          r.Errors,
+			ErrorRate:       r.ErrorRate,
+			BudgetExceeded:  r.BudgetExceeded,
+			ShortResults:    r.ShortResults,
 			Parallelization: r.Parallelization,
 			Took:            int64(r.Took),
 			TookFormatted:   fmt.Sprint(r.Took),
@@ -344,13 +656,22 @@ func (r Results) WriteJSONTo(w io.Writer) (int, error) {
 			"min":  fmt.Sprint(r.Min),
 		},
 		Throughput: resultsJSONThroughput{
-			QPS: r.QueriesPerSecond,
+			QPS:       r.QueriesPerSecond,
+			TargetQPS: r.TargetQPS,
 		},
+		Histogram: histogramBuckets(r.Histogram),
+	}
+
+	if r.QrelsCount > 0 {
+		obj.Quality = &resultsJSONQuality{retrievalQuality: r.Quality, Queries: r.QrelsCount}
+		if r.RecallCount > 0 {
+			obj.Quality.Recall = &r.Recall
+		}
 	}
 
 	for i, percentile := range targetPercentiles {
-		obj.Latencies[fmt.Sprintf("p%d", percentile)] = int64(r.Percentiles[i])
-		obj.LatenciesFormatted[fmt.Sprintf("p%d", percentile)] = fmt.Sprint(r.Percentiles[i])
+		obj.Latencies[percentileLabel(percentile)] = int64(r.Percentiles[i])
+		obj.LatenciesFormatted[percentileLabel(percentile)] = fmt.Sprint(r.Percentiles[i])
 	}
 
 	bytes, err := json.MarshalIndent(obj, "", "  ")
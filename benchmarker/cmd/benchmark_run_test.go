@@ -1,11 +1,26 @@
 package cmd
 
 import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/parquet-go/parquet-go"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
+	weaviategrpc "github.com/weaviate/weaviate/grpc/generated/protocol/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestUuidFromInt(t *testing.T) {
@@ -83,11 +98,24 @@ func TestAnalyzer(t *testing.T) {
 
 	recall := []float64{0.7, 0.8, 0.9, 0.7, 0.8, 0.9, 0.2}
 
+	latencies := newLatencyRecorder()
+	for _, d := range durations {
+		latencies.record(d)
+	}
+	for _, r := range recall {
+		latencies.recordRecall(r)
+	}
+	latencies.recordError(grpcQueryError(status.Error(codes.Unavailable, "unavailable")))
+	latencies.recordError(grpcQueryError(status.Error(codes.Unavailable, "unavailable")))
+	latencies.recordError(httpQueryError(503, errors.New("service unavailable")))
+
 	t.Run("check analyze accuracy", func(t *testing.T) {
-		results := analyze(c, durations, totalTime, recall)
+		results := analyze(c, latencies, totalTime)
 
 		require.Equal(t, 10, results.Total)
 		require.Equal(t, 3, results.Failed)
+		require.Equal(t, map[string]int{"Unavailable": 2, "http_503": 1}, results.Errors)
+		require.Equal(t, 0.3, results.ErrorRate)
 		require.Equal(t, time.Second*6, results.Max)
 		require.Equal(t, time.Second*0, results.Min)
 		require.Equal(t, time.Second*3, results.Mean)
@@ -98,3 +126,657 @@ func TestAnalyzer(t *testing.T) {
 	})
 
 }
+
+func TestMergeLatencyRecorders(t *testing.T) {
+	recorders := make([]*latencyRecorder, 4)
+	for i := range recorders {
+		recorders[i] = newLatencyRecorder()
+	}
+
+	// 10000 queries from 1ms to 10s spread over the workers
+	for i := 1; i <= 10000; i++ {
+		recorders[i%len(recorders)].record(time.Duration(i) * time.Millisecond)
+	}
+
+	merged := mergeLatencyRecorders(recorders)
+	require.Equal(t, 10000, merged.count)
+	require.Equal(t, time.Millisecond, merged.min)
+	require.Equal(t, 10*time.Second, merged.max)
+
+	for _, percentile := range []float64{50, 99, 99.9, 99.99} {
+		expected := time.Duration(percentile*100) * time.Millisecond
+		actual := histogramPercentile(merged.histogram, percentile)
+		require.InEpsilon(t, float64(expected), float64(actual), 0.001, "p%v", percentile)
+	}
+
+	buckets := histogramBuckets(merged.histogram)
+	var total int64
+	for _, b := range buckets {
+		total += b.Count
+	}
+	require.Equal(t, int64(10000), total)
+}
+
+func TestErrorBudget(t *testing.T) {
+	budget := newErrorBudget(&Config{MaxErrorRate: 0.1})
+	for i := 0; i < 9; i++ {
+		budget.success()
+	}
+	require.False(t, budget.finish())
+
+	// too few queries to abort while running, but reported at the end
+	budget.failure(errors.New("failed"))
+	budget.failure(errors.New("failed"))
+	require.True(t, budget.finish())
+}
+
+func TestMergeSampledCounts(t *testing.T) {
+	var samples sampledResults
+	for i := 1; i <= 2; i++ {
+		latencies := newLatencyRecorder()
+		for j := 0; j < 10; j++ {
+			latencies.record(time.Duration(i) * time.Second)
+		}
+		if i == 1 {
+			latencies.recordError(grpcQueryError(status.Error(codes.Unavailable, "unavailable")))
+		}
+		latencies.recordScore(queryScore{recall: 1, hasRecall: true, shortResults: true})
+		samples.Results = append(samples.Results, analyze(Config{Queries: 11}, latencies, time.Second))
+	}
+
+	var out Results
+	samples.mergeCounts(&out)
+	require.Equal(t, 22, out.Total)
+	require.Equal(t, 20, out.Successful)
+	require.Equal(t, 1, out.Failed)
+	require.Equal(t, map[string]int{"Unavailable": 1}, out.Errors)
+	require.Equal(t, 1.0/21.0, out.ErrorRate)
+	require.Equal(t, 2, out.ShortResults)
+	require.Equal(t, 2, out.RecallCount)
+	require.Zero(t, out.QrelsCount)
+	require.InEpsilon(t, float64(time.Second), float64(out.Percentile(50)), 0.001)
+	require.InEpsilon(t, float64(2*time.Second), float64(out.Percentile(99)), 0.001)
+}
+
+func TestTimeline(t *testing.T) {
+	tl := newTimeline(time.Second)
+	tl.start = time.Now().Add(-2500 * time.Millisecond)
+
+	latencies := newLatencyRecorder()
+	latencies.timeline = tl
+	latencies.record(10 * time.Millisecond)
+	latencies.recordRecall(0.5)
+	latencies.record(30 * time.Millisecond)
+	latencies.recordRecall(1)
+	latencies.recordError(errors.New("failed"))
+	mergeLatencyRecorders([]*latencyRecorder{latencies})
+
+	records := tl.records(tl.start.Add(3 * time.Second))
+	require.Len(t, records, 3)
+	require.Equal(t, 0, records[0].Queries)
+	require.Equal(t, 2, records[2].Queries)
+	require.Equal(t, 1, records[2].Errors)
+	require.InDelta(t, 2.0, records[2].QPS, 0.001)
+	require.InDelta(t, 0.75, records[2].Recall, 0.001)
+	require.InDelta(t, 0.03, records[2].Max, 0.001)
+
+	rolling := newRollingTimeline(time.Second, 3)
+	for idx := 0; idx < 10; idx++ {
+		b := newTimelineBucket()
+		b.count = 1
+		rolling.merge(idx, b)
+	}
+	for idx, b := range rolling.buckets {
+		require.Equal(t, idx >= 6, b != nil, "bucket %d", idx)
+	}
+	// dropped buckets are reused for the next interval
+	require.Len(t, rolling.free, 1)
+}
+
+func TestImportStats(t *testing.T) {
+	stats := newImportStats()
+	stats.recordBatch(100*time.Millisecond, 1000, nil)
+	stats.recordBatch(300*time.Millisecond, 1000, []string{"vector lengths don't match", "vector lengths don't match"})
+	stats.attempts.Add(3)
+
+	results := stats.results()
+	require.Equal(t, 2000, results.Objects)
+	require.Equal(t, int64(2), results.Batches)
+	require.Equal(t, 1, results.FailedBatches)
+	require.Equal(t, 2, results.ObjectErrors)
+	require.Equal(t, map[string]int{"vector lengths don't match": 2}, results.Errors)
+	require.Equal(t, int64(1), results.Retries)
+	require.InDelta(t, 0.3, results.BatchLatencies["max"], 0.001)
+	require.Len(t, results.Throughput, 1)
+	require.Equal(t, 2000, results.Throughput[0].Objects)
+}
+
+func TestTexmexSource(t *testing.T) {
+	dir := t.TempDir()
+
+	writeVecs := func(name string, dims int, rows [][]byte) {
+		var buf []byte
+		for _, row := range rows {
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(dims))
+			buf = append(buf, row...)
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), buf, 0o644))
+	}
+	float32Row := func(values ...float32) []byte {
+		var buf []byte
+		for _, v := range values {
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(v))
+		}
+		return buf
+	}
+
+	writeVecs("sift_base.bvecs", 2, [][]byte{{1, 2}, {3, 4}, {5, 255}})
+	writeVecs("sift_query.fvecs", 2, [][]byte{float32Row(0.5, 1.5)})
+	writeVecs("sift_groundtruth.ivecs", 2, [][]byte{float32Row(math.Float32frombits(1), math.Float32frombits(0))})
+
+	cfg := &Config{BenchmarkFile: filepath.Join(dir, "sift_base.bvecs"), BatchSize: 2}
+	source := openDatasetSource(cfg)
+	defer source.Close()
+
+	rows, dimensions := source.trainExtent(cfg)
+	require.Equal(t, uint(3), rows)
+	require.Equal(t, uint(2), dimensions)
+
+	chunks := make(chan Batch, 10)
+	source.streamTrain(chunks, cfg, 1, 0, nil)
+	close(chunks)
+
+	var batches []Batch
+	for chunk := range chunks {
+		batches = append(batches, chunk)
+	}
+	require.Len(t, batches, 1)
+	require.Equal(t, 1, batches[0].Offset)
+	require.Equal(t, [][]float32{{3, 4}, {5, 255}}, batches[0].Vectors)
+
+	require.Equal(t, [][]float32{{0.5, 1.5}}, source.testVectors(cfg, nil))
+	require.Equal(t, [][]int{{1, 0}}, source.neighbors(cfg, nil))
+}
+
+func TestBigannSource(t *testing.T) {
+	dir := t.TempDir()
+
+	writeBin := func(name string, rows, dims int, payload []byte) string {
+		buf := binary.LittleEndian.AppendUint32(nil, uint32(rows))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(dims))
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, append(buf, payload...), 0o644))
+		return path
+	}
+	uint32Row := func(values ...uint32) []byte {
+		var buf []byte
+		for _, v := range values {
+			buf = binary.LittleEndian.AppendUint32(buf, v)
+		}
+		return buf
+	}
+
+	base := writeBin("base.1B.u8bin.crop_nb_3", 3, 2, []byte{1, 2, 3, 4, 5, 255})
+	query := writeBin("query.public.10K.u8bin", 1, 2, []byte{7, 8})
+	// ids followed by the distances
+	gt := writeBin("gt.ibin", 1, 2, uint32Row(1, 0, math.Float32bits(0.5), math.Float32bits(1)))
+
+	cfg := &Config{BenchmarkFile: base, TestVectorsFile: query, NeighborsFile: gt, BatchSize: 2}
+	source := openDatasetSource(cfg)
+	defer source.Close()
+
+	rows, dimensions := source.trainExtent(cfg)
+	require.Equal(t, uint(3), rows)
+	require.Equal(t, uint(2), dimensions)
+
+	chunks := make(chan Batch, 10)
+	source.streamTrain(chunks, cfg, 0, 0, nil)
+	close(chunks)
+
+	var vectors [][]float32
+	for chunk := range chunks {
+		vectors = append(vectors, chunk.Vectors...)
+	}
+	require.Equal(t, [][]float32{{1, 2}, {3, 4}, {5, 255}}, vectors)
+
+	require.Equal(t, [][]float32{{7, 8}}, source.testVectors(cfg, nil))
+	require.Equal(t, [][]int{{1, 0}}, source.neighbors(cfg, nil))
+	cfg.DistanceMetric = "l2-squared"
+	require.Equal(t, [][]float32{{0.5, 1}}, source.distances(cfg, nil))
+	cfg.DistanceMetric = "dot"
+	require.Equal(t, [][]float32{{-0.5, -1}}, source.distances(cfg, nil))
+	cfg.NeighborsFile = writeBin("ids.ibin", 1, 2, uint32Row(1, 0))
+	idsOnly := openDatasetSource(cfg)
+	require.Nil(t, idsOnly.distances(cfg, nil))
+	idsOnly.Close()
+
+	_, err := openBinFile(writeBin("short.fbin", 2, 2, uint32Row(1, 2)))
+	require.Error(t, err)
+}
+
+func TestParquetSource(t *testing.T) {
+	dir := t.TempDir()
+
+	type trainRow struct {
+		Vector []float32 `parquet:"vector,list"`
+		Brand  string    `parquet:"brand"`
+		Price  float64   `parquet:"price,optional"` // zero is written as null
+		Tags   []string  `parquet:"tags,list"`
+	}
+	type testRow struct {
+		Vector []float32 `parquet:"vector,list"`
+	}
+	type neighborsRow struct {
+		Neighbors []int64 `parquet:"neighbors,list"`
+	}
+
+	train := []trainRow{
+		{Vector: []float32{1, 2}, Brand: "a", Price: 1.5, Tags: []string{"x"}},
+		{Vector: []float32{3, 4}, Brand: "b", Price: 2, Tags: []string{"x", "y"}},
+		{Vector: []float32{5, 6}, Brand: "c"},
+	}
+	cfg := &Config{
+		BenchmarkFile:   filepath.Join(dir, "train.parquet"),
+		TestVectorsFile: filepath.Join(dir, "test.parquet"),
+		NeighborsFile:   filepath.Join(dir, "neighbors.parquet"),
+		VectorColumn:    "vector",
+		PropertyColumns: []string{"brand", "price", "tags"},
+		BatchSize:       2,
+	}
+	require.NoError(t, parquet.WriteFile(cfg.BenchmarkFile, train, parquet.MaxRowsPerRowGroup(2)))
+	require.NoError(t, parquet.WriteFile(cfg.TestVectorsFile, []testRow{{Vector: []float32{0.5, 1.5}}}))
+	require.NoError(t, parquet.WriteFile(cfg.NeighborsFile, []neighborsRow{{Neighbors: []int64{2, 0}}}))
+
+	source := openDatasetSource(cfg)
+	defer source.Close()
+
+	rows, dimensions := source.trainExtent(cfg)
+	require.Equal(t, uint(3), rows)
+	require.Equal(t, uint(2), dimensions)
+
+	chunks := make(chan Batch, 10)
+	source.streamTrain(chunks, cfg, 1, 0, nil)
+	close(chunks)
+
+	var batches []Batch
+	for chunk := range chunks {
+		batches = append(batches, chunk)
+	}
+	require.Len(t, batches, 1)
+	require.Equal(t, 1, batches[0].Offset)
+	require.Equal(t, [][]float32{{3, 4}, {5, 6}}, batches[0].Vectors)
+	require.Equal(t, []map[string]interface{}{
+		{"brand": "b", "price": 2.0, "tags": []interface{}{"x", "y"}},
+		{"brand": "c"},
+	}, batches[0].Properties)
+
+	require.Equal(t, [][]float32{{0.5, 1.5}}, source.testVectors(cfg, nil))
+	require.Equal(t, [][]int{{2, 0}}, source.neighbors(cfg, nil))
+}
+
+func TestNpySource(t *testing.T) {
+	dir := t.TempDir()
+
+	npy := func(descr, shape string, data []byte) []byte {
+		header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': %s, }", descr, shape)
+		header += strings.Repeat(" ", 63-(10+len(header))%64) + "\n"
+		buf := append([]byte("\x93NUMPY\x01\x00"), binary.LittleEndian.AppendUint16(nil, uint16(len(header)))...)
+		return append(append(buf, header...), data...)
+	}
+	values := func(bits ...uint64) func(size int) []byte {
+		return func(size int) []byte {
+			var buf []byte
+			for _, b := range bits {
+				switch size {
+				case 2:
+					buf = binary.LittleEndian.AppendUint16(buf, uint16(b))
+				case 4:
+					buf = binary.LittleEndian.AppendUint32(buf, uint32(b))
+				default:
+					buf = binary.LittleEndian.AppendUint64(buf, b)
+				}
+			}
+			return buf
+		}
+	}
+
+	arrays := map[string][]byte{
+		// 1, 2, 0.5, -2 and 65504 as float16
+		"train.npy":     npy("<f2", "(3, 2)", values(0x3c00, 0x4000, 0x3800, 0xc000, 0x7bff, 0)(2)),
+		"test.npy":      npy("<f8", "(1, 2)", values(math.Float64bits(0.5), math.Float64bits(1.5))(8)),
+		"neighbors.npy": npy("<i4", "(1, 2)", values(2, 0)(4)),
+	}
+
+	archive, err := os.Create(filepath.Join(dir, "dataset.npz"))
+	require.NoError(t, err)
+	w := zip.NewWriter(archive)
+	for name, data := range arrays {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		require.NoError(t, err)
+		_, err = f.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o644))
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, archive.Close())
+
+	for _, path := range []string{filepath.Join(dir, "dataset.npz"), dir} {
+		cfg := &Config{BenchmarkFile: path, BatchSize: 2}
+		source := openDatasetSource(cfg)
+
+		rows, dimensions := source.trainExtent(cfg)
+		require.Equal(t, uint(3), rows)
+		require.Equal(t, uint(2), dimensions)
+
+		chunks := make(chan Batch, 10)
+		source.streamTrain(chunks, cfg, 1, 0, nil)
+		close(chunks)
+
+		var batches []Batch
+		for chunk := range chunks {
+			batches = append(batches, chunk)
+		}
+		require.Len(t, batches, 1)
+		require.Equal(t, 1, batches[0].Offset)
+		require.Equal(t, [][]float32{{0.5, -2}, {65504, 0}}, batches[0].Vectors)
+
+		require.Equal(t, [][]float32{{0.5, 1.5}}, source.testVectors(cfg, nil))
+		require.Equal(t, [][]int{{2, 0}}, source.neighbors(cfg, nil))
+		require.Equal(t, 1, source.testCount(cfg))
+
+		selected, err := source.(*npySource).array("train").float32Rows([]int{0, 2})
+		require.NoError(t, err)
+		require.Equal(t, [][]float32{{1, 2}, {65504, 0}}, selected)
+		source.Close()
+	}
+
+	require.Equal(t, float32(5.9604645e-08), float16ToFloat32(0x0001))
+	require.True(t, math.IsInf(float64(float16ToFloat32(0xfc00)), -1))
+}
+
+func TestGroundTruth(t *testing.T) {
+	a, b := []float32{1, 0, 2}, []float32{0, 3, 2}
+	for metric, expected := range map[string]float32{
+		"cosine": 1 - 4/float32(math.Sqrt(5)*math.Sqrt(13)), "dot": -4, "l2-squared": 10, "hamming": 2, "manhattan": 4,
+	} {
+		distance, err := newDistanceFunc(metric)
+		require.NoError(t, err)
+		require.InDelta(t, expected, distance(a, b), 1e-6, metric)
+	}
+	_, err := newDistanceFunc("l1")
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	var train []byte
+	for i := 0; i < 10; i++ {
+		train = binary.LittleEndian.AppendUint32(train, 1)
+		train = append(train, byte(i*10))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base.bvecs"), train, 0o644))
+
+	cfg := &Config{BenchmarkFile: filepath.Join(dir, "base.bvecs"), BatchSize: 3, Parallel: 2, Limit: 3}
+	source := openDatasetSource(cfg)
+	defer source.Close()
+
+	l2, _ := newDistanceFunc("l2-squared")
+	neighbors, distances := computeGroundTruth(source, cfg, [][]float32{{42}, {0}, {95}}, nil, nil, l2)
+	require.Equal(t, [][]int{{4, 5, 3}, {0, 1, 2}, {9, 8, 7}}, neighbors)
+	require.Equal(t, []float32{4, 64, 144}, distances[0])
+
+	// Only even rows share the category of the first query, the second query
+	// has fewer than 3 candidates
+	trainCategories := []int{1, 2, 1, 2, 1, 2, 1, 2, 1, 3}
+	neighbors, distances = computeGroundTruth(source, cfg, [][]float32{{42}, {0}}, trainCategories, []int{1, 3}, l2)
+	require.Equal(t, [][]int{{4, 6, 2}, {9, -1, -1}}, neighbors)
+	require.Equal(t, float32(math.MaxFloat32), distances[1][2])
+
+	rng := rand.New(rand.NewSource(1))
+	for _, distribution := range []string{"uniform", "normal"} {
+		categories := generateCategories(1000, 10, distribution, rng)
+		require.Len(t, categories, 1000)
+		for _, c := range categories {
+			require.True(t, c >= 1 && c <= 10, distribution)
+		}
+	}
+}
+
+func TestQueryRows(t *testing.T) {
+	require.Nil(t, queryRows(&Config{}, 10))
+	require.Nil(t, queryRows(&Config{MaxQueries: 10}, 10))
+	require.Equal(t, []int{0, 1, 2}, queryRows(&Config{MaxQueries: 3}, 10))
+
+	cfg := &Config{MaxQueries: 4, SampleQueries: true, Seed: 3}
+	sample := queryRows(cfg, 100)
+	require.Len(t, sample, 4)
+	require.True(t, slices.IsSorted(sample))
+	require.Equal(t, sample, queryRows(cfg, 100))
+
+	var reads [][2]int
+	values, err := readRowRuns([]int{1, 2, 3, 7, 9, 10}, 12, func(start, count int) ([]int, error) {
+		reads = append(reads, [2]int{start, count})
+		out := make([]int, count)
+		for i := range out {
+			out[i] = start + i
+		}
+		return out, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3, 7, 9, 10}, values)
+	require.Equal(t, [][2]int{{1, 3}, {7, 1}, {9, 2}}, reads)
+
+	_, err = readRowRuns([]int{1, 12}, 12, func(start, count int) ([]int, error) {
+		return make([]int, count), nil
+	})
+	require.Error(t, err)
+	require.NoError(t, checkQueryRows(nil, 0))
+
+	require.Equal(t, []string{"b", "d"}, pickRows([]string{"a", "b", "c", "d"}, []int{1, 3}))
+}
+
+func TestMixtureGenerator(t *testing.T) {
+	cfg := Config{Generator: "gaussian-mixture", Dimensions: 16, IntrinsicDimensions: 4, Clusters: 3,
+		ClusterSpread: 0.3, Normalize: true, Seed: 7}
+
+	first := newMixtureGenerator(&cfg)
+	second := newMixtureGenerator(&cfg)
+	for i := 0; i < 10; i++ {
+		vector := first.vector()
+		require.Len(t, vector, 16)
+		require.Equal(t, vector, second.vector())
+
+		norm := 0.0
+		for _, v := range vector {
+			norm += float64(v) * float64(v)
+		}
+		require.InDelta(t, 1, norm, 1e-5)
+	}
+
+	cfg.Mode, cfg.OutputFile, cfg.TrainSize, cfg.TestSize = "generate-dataset", "out.hdf5", 100, 10
+	cfg.DistanceMetric, cfg.Limit, cfg.Parallel, cfg.BatchSize = "l2-squared", 10, 1, 10
+	require.NoError(t, cfg.Validate())
+	cfg.IntrinsicDimensions = 17
+	require.Error(t, cfg.Validate())
+}
+
+func TestScoreQuery(t *testing.T) {
+	cfg := &Config{Limit: 3, RecallEpsilon: 1e-3}
+	query := QueryWithNeighbors{Neighbors: []int{1, 2, 3}}
+
+	// ids only, 4 ties with 3 at the k-th distance but is not a neighbor
+	score := scoreQuery(cfg, query, []int{1, 2, 4}, nil)
+	require.InDelta(t, 2.0/3, score.recall, 1e-9)
+	require.True(t, score.hasRecall)
+	require.False(t, score.hasDistances)
+
+	query.Distances = []float32{0.1, 0.2, 0.3}
+	score = scoreQuery(cfg, query, []int{1, 2, 4}, []float32{0.1, 0.2, 0.3})
+	require.Equal(t, 1.0, score.recall)
+	require.True(t, score.hasDistances)
+	require.InDelta(t, 0, score.distanceError, 1e-6)
+
+	score = scoreQuery(cfg, query, []int{1, 2, 5}, []float32{0.1, 0.2, 0.6})
+	require.InDelta(t, 2.0/3, score.recall, 1e-9)
+	require.InDelta(t, 0.5, score.distanceError, 1e-6)
+}
+
+func TestHybridQueryGrpc(t *testing.T) {
+	cfg := &Config{ClassName: "Vector", Limit: 10, Alpha: 0.5, FusionType: "ranked", QueryProperties: []string{"title"}}
+
+	spec, err := parseFilterSpec([]byte(categoryFilterSpec))
+	require.NoError(t, err)
+	filters, err := spec.filters(map[string]interface{}{"category": "3"})
+	require.NoError(t, err)
+
+	var request weaviategrpc.SearchRequest
+	require.NoError(t, proto.Unmarshal(hybridQueryGrpc(cfg, []float32{1, 2}, "red shoes", "", filters), &request))
+	require.Nil(t, request.NearVector)
+	require.Equal(t, "red shoes", request.HybridSearch.Query)
+	require.Equal(t, []string{"title"}, request.HybridSearch.Properties)
+	require.Equal(t, float32(0.5), request.HybridSearch.Alpha)
+	require.Equal(t, weaviategrpc.Hybrid_FUSION_TYPE_RANKED, request.HybridSearch.FusionType)
+	require.Equal(t, encodeVector([]float32{1, 2}), request.HybridSearch.VectorBytes)
+	require.Equal(t, "3", request.Filters.GetValueText())
+	require.True(t, request.Metadata.Uuid)
+}
+
+func TestScoreQrels(t *testing.T) {
+	cfg := &Config{Limit: 3}
+	query := QueryWithNeighbors{Qrels: map[int]int{7: 2, 8: 1, 9: 0, 10: 1}}
+
+	// ideal order 7, 8, 10 with gains 2, 1, 1
+	idcg := 2 + 1/math.Log2(3) + 1/math.Log2(4)
+	score := scoreQuery(cfg, query, []int{9, 8, 7}, nil)
+	require.True(t, score.hasQrels)
+	require.InDelta(t, 2.0/3, score.recall, 1e-9)
+	require.InDelta(t, (1/math.Log2(3)+2/math.Log2(4))/idcg, score.quality.NDCG, 1e-9)
+	require.InDelta(t, 0.5, score.quality.MRR, 1e-9)
+	require.InDelta(t, (1.0/2+2.0/3)/3, score.quality.MAP, 1e-9)
+	require.InDelta(t, 2.0/3, score.quality.Precision, 1e-9)
+
+	score = scoreQuery(cfg, query, []int{7, 10, 8}, nil)
+	require.Equal(t, 1.0, score.recall)
+	require.Equal(t, retrievalQuality{NDCG: 1, MRR: 1, MAP: 1, Precision: 1}, score.quality)
+
+	// neighbor recall is kept next to the quality of vector queries
+	query.Neighbors = []int{1, 2, 7}
+	score = scoreQuery(cfg, query, []int{1, 2, 3}, nil)
+	require.InDelta(t, 2.0/3, score.recall, 1e-9)
+	require.True(t, score.hasQrels)
+	require.Equal(t, 0.0, score.quality.NDCG)
+
+	// keyword queries without neighbors or judged rows have no recall
+	score = scoreQuery(cfg, QueryWithNeighbors{Qrels: map[int]int{9: 0}}, []int{9}, nil)
+	require.False(t, score.hasRecall)
+	require.False(t, score.hasQrels)
+
+	latencies := newLatencyRecorder()
+	latencies.record(time.Millisecond)
+	latencies.recordScore(score)
+	results := analyze(Config{Queries: 1}, latencies, time.Second)
+	require.Zero(t, results.RecallCount)
+	var out strings.Builder
+	_, err := results.WriteTextTo(&out)
+	require.NoError(t, err)
+	require.NotContains(t, out.String(), "Recall")
+}
+
+func TestFilterSpec(t *testing.T) {
+	spec, err := parseFilterSpec([]byte(`{"operator": "And", "operands": [
+		{"operator": "Or", "operands": [
+			{"path": ["price"], "operator": "LessThanEqual", "valueNumber": "{{maxPrice}}"},
+			{"path": ["stock"], "operator": "GreaterThan", "valueInt": 0}]},
+		{"path": ["published"], "operator": "GreaterThanEqual", "valueDate": "2024-01-01T00:00:00Z"},
+		{"path": ["tags"], "operator": "ContainsAny", "valueTextArray": "{{tags}}"}]}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"maxPrice", "tags"}, spec.placeholders())
+
+	values, err := decodeProperties([]byte(`{"maxPrice": 9.5, "tags": ["a", "b"]}`))
+	require.NoError(t, err)
+	filters, err := spec.filters(values)
+	require.NoError(t, err)
+
+	require.Equal(t, weaviategrpc.Filters_OPERATOR_AND, filters.Operator)
+	require.Len(t, filters.Filters, 3)
+	or := filters.Filters[0]
+	require.Equal(t, weaviategrpc.Filters_OPERATOR_OR, or.Operator)
+	require.Equal(t, 9.5, or.Filters[0].GetValueNumber())
+	require.Equal(t, []string{"price"}, or.Filters[0].On)
+	require.Equal(t, int64(0), or.Filters[1].GetValueInt())
+	require.Equal(t, "2024-01-01T00:00:00Z", filters.Filters[1].GetValueText())
+	require.Equal(t, weaviategrpc.Filters_OPERATOR_CONTAINS_ANY, filters.Filters[2].Operator)
+	require.Equal(t, []string{"a", "b"}, filters.Filters[2].GetValueTextArray().Values)
+
+	_, err = spec.filters(map[string]interface{}{"maxPrice": 1})
+	require.ErrorContains(t, err, "tags")
+
+	_, err = parseFilterSpec([]byte(`{"path": ["price"], "operator": "LessThan", "valueNumbr": 1}`))
+	require.Error(t, err)
+}
+
+func TestSelectivities(t *testing.T) {
+	cardinalities, err := parseSelectivities("0.5, 0.001,0.1,0.01,0.1")
+	require.NoError(t, err)
+	require.Equal(t, []int{1000, 100, 10, 2}, cardinalities)
+
+	_, err = parseSelectivities("0.1,0")
+	require.Error(t, err)
+
+	counts := make([]int, 10)
+	for row := 0; row < 100000; row++ {
+		value := cardinalityValue(row, 10, trainCardinalitySalt)
+		counts[value]++
+	}
+	for _, count := range counts {
+		require.InDelta(t, 10000, count, 500)
+	}
+}
+
+func TestParseWhereFilter(t *testing.T) {
+	graphQL := `, where: {operator: And, operands: [
+		{path: [\"category\"], operator: Equal, valueText: \"5\"},
+		{path: [\"price\"], operator: LessThan, valueNumber: -1.5e2}]}`
+	filters, err := parseWhereFilter(graphQL)
+	require.NoError(t, err)
+	require.Equal(t, weaviategrpc.Filters_OPERATOR_AND, filters.Operator)
+	require.Equal(t, []string{"category"}, filters.Filters[0].On)
+	require.Equal(t, "5", filters.Filters[0].GetValueText())
+	require.Equal(t, weaviategrpc.Filters_OPERATOR_LESS_THAN, filters.Filters[1].Operator)
+	require.Equal(t, -150.0, filters.Filters[1].GetValueNumber())
+
+	path := filepath.Join(t.TempDir(), "where.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"path": ["tags"], "operator": "ContainsAny", "valueTextArray": ["a, b"]}`), 0o644))
+	filters, err = parseWhereFilter(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a, b"}, filters.GetValueTextArray().Values)
+
+	// escaped quotes of JSON values are kept
+	filters, err = parseWhereFilter(`{"path": ["title"], "operator": "Equal", "valueText": "a \"b\""}`)
+	require.NoError(t, err)
+	require.Equal(t, `a "b"`, filters.GetValueText())
+
+	_, err = parseWhereFilter(`{path: ["x"], operator: Equals, valueInt: 1}`)
+	require.Error(t, err)
+}
+
+func TestFlagDefaults(t *testing.T) {
+	defer func(cfg Config) { globalConfig = cfg }(globalConfig)
+
+	// every command runs with the defaults shown in its help, not those of
+	// the command that registered a shared field last
+	for _, c := range rootCmd.Commands() {
+		require.NoError(t, applyFlagDefaults(c))
+		c.LocalFlags().VisitAll(func(f *pflag.Flag) {
+			require.Equal(t, f.DefValue, f.Value.String(), "%s --%s", c.Name(), f.Name)
+		})
+	}
+
+	require.NoError(t, applyFlagDefaults(annBenchmarkCommand))
+	require.Equal(t, "", globalConfig.DistanceMetric)
+	require.Equal(t, 10, globalConfig.Limit)
+
+	require.NoError(t, qpsSweepCommand.ParseFlags([]string{"--parallel", "3"}))
+	defer func() { qpsSweepCommand.Flags().Lookup("parallel").Changed = false }()
+	require.NoError(t, applyFlagDefaults(qpsSweepCommand))
+	require.Equal(t, 3, globalConfig.Parallel)
+	require.Equal(t, 1.0, globalConfig.MaxErrorRate)
+}
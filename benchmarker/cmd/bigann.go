@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/exp/mmap"
+)
+
+// A big-ann-benchmarks (https://big-ann-benchmarks.com) binary file. The
+// header holds the number of rows and dimensions as little endian uint32,
+// followed by the row-major payload. Files are memory mapped so billion
+// scale base sets are read straight from the page cache.
+type binFile struct {
+	data          *mmap.ReaderAt
+	path          string
+	componentSize int
+	kind          string
+	rows          int
+	dimensions    int
+}
+
+// The component type of a file is picked by its extension. Cropped subsets
+// such as base.1B.u8bin.crop_nb_10000000 keep it in the middle of the name.
+func binFileKind(path string) string {
+	base := strings.ToLower(filepath.Base(path))
+	for _, kind := range []string{".fbin", ".u8bin", ".i8bin", ".ibin"} {
+		if strings.HasSuffix(base, kind) || strings.Contains(base, kind+".") {
+			return kind
+		}
+	}
+	return ""
+}
+
+func openBinFile(path string) (*binFile, error) {
+	b := &binFile{path: path, kind: binFileKind(path)}
+	switch b.kind {
+	case ".fbin", ".ibin":
+		b.componentSize = 4
+	case ".u8bin", ".i8bin":
+		b.componentSize = 1
+	default:
+		return nil, errors.Errorf("%s: unsupported extension, expected .fbin, .u8bin, .i8bin or .ibin", path)
+	}
+
+	data, err := mmap.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	b.data = data
+
+	header := make([]byte, 8)
+	if _, err := data.ReadAt(header, 0); err != nil {
+		data.Close()
+		return nil, errors.Wrapf(err, "%s: read header", path)
+	}
+	b.rows = int(binary.LittleEndian.Uint32(header[0:]))
+	b.dimensions = int(binary.LittleEndian.Uint32(header[4:]))
+
+	if payload := b.rows * b.dimensions * b.componentSize; data.Len() < 8+payload {
+		data.Close()
+		return nil, errors.Errorf("%s: size %d is too small for %d rows of %d dimensions",
+			path, data.Len(), b.rows, b.dimensions)
+	}
+
+	return b, nil
+}
+
+func (b *binFile) rowSize() int {
+	return b.dimensions * b.componentSize
+}
+
+// Read count rows starting at row start, offset is the byte offset of the
+// section to read from
+func (b *binFile) read(offset int64, start, count int) ([]byte, error) {
+	buf := make([]byte, count*b.rowSize())
+	if n, err := b.data.ReadAt(buf, offset+int64(start)*int64(b.rowSize())); n < len(buf) {
+		return nil, errors.Errorf("%s: read rows %d to %d: %v", b.path, start, start+count, err)
+	}
+	return buf, nil
+}
+
+// Read rows as vectors, uint8 and int8 components are converted to float32
+func (b *binFile) float32Rows(start, count int) ([][]float32, error) {
+	if b.kind == ".ibin" {
+		return nil, errors.Errorf("%s: expected a vector file", b.path)
+	}
+	buf, err := b.read(8, start, count)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([][]float32, count)
+	for i := range out {
+		row := buf[i*b.rowSize() : (i+1)*b.rowSize()]
+		out[i] = make([]float32, b.dimensions)
+		for j := range out[i] {
+			switch b.kind {
+			case ".u8bin":
+				out[i][j] = float32(row[j])
+			case ".i8bin":
+				out[i][j] = float32(int8(row[j]))
+			default:
+				out[i][j] = math.Float32frombits(binary.LittleEndian.Uint32(row[j*4:]))
+			}
+		}
+	}
+	return out, nil
+}
+
+// Read rows of neighbor ids of a ground truth .ibin file. Ground truth files
+// of big-ann-benchmarks store the distances as float32 after the ids.
+func (b *binFile) neighbors(start, count int) ([][]int, error) {
+	if b.kind != ".ibin" {
+		return nil, errors.Errorf("%s: expected an .ibin ground truth file", b.path)
+	}
+	buf, err := b.read(8, start, count)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([][]int, count)
+	for i := range out {
+		row := buf[i*b.rowSize() : (i+1)*b.rowSize()]
+		out[i] = make([]int, b.dimensions)
+		for j := range out[i] {
+			out[i][j] = int(int32(binary.LittleEndian.Uint32(row[j*4:])))
+		}
+	}
+	return out, nil
+}
+
+// Whether a ground truth file holds the float32 distances block after the
+// ids, files cropped to the ids only have none
+func (b *binFile) hasDistances() bool {
+	return b.kind == ".ibin" && b.data.Len() >= 8+2*b.rows*b.rowSize()
+}
+
+// Read rows of neighbor distances of a ground truth .ibin file from the
+// float32 block after the ids
+func (b *binFile) distances(start, count int) ([][]float32, error) {
+	if !b.hasDistances() {
+		return nil, errors.Errorf("%s: expected an .ibin ground truth file with distances", b.path)
+	}
+	buf, err := b.read(8+int64(b.rows)*int64(b.rowSize()), start, count)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([][]float32, count)
+	for i := range out {
+		row := buf[i*b.rowSize() : (i+1)*b.rowSize()]
+		out[i] = make([]float32, b.dimensions)
+		for j := range out[i] {
+			out[i][j] = math.Float32frombits(binary.LittleEndian.Uint32(row[j*4:]))
+		}
+	}
+	return out, nil
+}
+
+func (b *binFile) Close() {
+	b.data.Close()
+}
+
+// A big-ann-benchmarks dataset, the base vectors are streamed from
+// cfg.BenchmarkFile, test vectors and ground truth are separate files
+type bigannSource struct {
+	train         *binFile
+	testPath      string
+	neighborsPath string
+}
+
+func openBigannSource(cfg *Config) *bigannSource {
+	if cfg.MultiVectorDimensions > 0 {
+		log.Fatalf("multi-vector datasets are only supported in hdf5 format")
+	}
+
+	train, err := openBinFile(cfg.BenchmarkFile)
+	if err != nil {
+		log.Fatalf("Error opening dataset: %v", err)
+	}
+
+	return &bigannSource{
+		train:         train,
+		testPath:      cfg.TestVectorsFile,
+		neighborsPath: cfg.NeighborsFile,
+	}
+}
+
+func (s *bigannSource) trainExtent(cfg *Config) (uint, uint) {
+	return uint(s.train.rows), uint(s.train.dimensions)
+}
+
+func (s *bigannSource) streamTrain(chunks chan<- Batch, cfg *Config, startOffset uint, maxRecords uint, filters []int) {
+	rows := uint(s.train.rows)
+
+	i := uint(0)
+	if maxRecords != 0 && maxRecords < rows {
+		rows = maxRecords
+	}
+
+	if startOffset != 0 && i < rows {
+		i = startOffset
+	}
+
+	batchSize := uint(cfg.BatchSize)
+
+	log.WithFields(log.Fields{"rows": rows, "dimensions": s.train.dimensions}).Printf(
+		"Reading big-ann-benchmarks dataset")
+
+	for ; i < rows; i += batchSize {
+		batchRows := min(batchSize, rows-i)
+
+		chunkData, err := s.train.float32Rows(int(i), int(batchRows))
+		if err != nil {
+			log.Fatalf("Error reading dataset: %v", err)
+		}
+
+		chunks <- Batch{Vectors: chunkData, Offset: int(i), Filters: []int{}}
+	}
+}
+
+func (s *bigannSource) openTest() *binFile {
+	if s.testPath == "" {
+		log.Fatalf("--testVectors must be set for big-ann-benchmarks datasets")
+	}
+	test, err := openBinFile(s.testPath)
+	if err != nil {
+		log.Fatalf("Error opening test vectors: %v", err)
+	}
+	return test
+}
+
+func (s *bigannSource) testCount(cfg *Config) int {
+	test := s.openTest()
+	defer test.Close()
+	return test.rows
+}
+
+func (s *bigannSource) testVectors(cfg *Config, rows []int) [][]float32 {
+	test := s.openTest()
+	defer test.Close()
+
+	if test.dimensions != s.train.dimensions {
+		log.Fatalf("Test vectors have %d dimensions, base vectors %d", test.dimensions, s.train.dimensions)
+	}
+
+	vectors, err := readRowRuns(rows, test.rows, test.float32Rows)
+	if err != nil {
+		log.Fatalf("Error reading test vectors: %v", err)
+	}
+	return vectors
+}
+
+func (s *bigannSource) neighbors(cfg *Config, rows []int) [][]int {
+	if s.neighborsPath == "" {
+		log.Fatalf("--neighbors must be set to the ground truth .ibin of big-ann-benchmarks datasets")
+	}
+	gt, err := openBinFile(s.neighborsPath)
+	if err != nil {
+		log.Fatalf("Error opening neighbors: %v", err)
+	}
+	defer gt.Close()
+
+	neighbors, err := readRowRuns(rows, gt.rows, gt.neighbors)
+	if err != nil {
+		log.Fatalf("Error reading neighbors: %v", err)
+	}
+	return neighbors
+}
+
+// The ground truth of big-ann-benchmarks is computed by faiss, so L2
+// datasets store squared euclidean distances and inner product datasets the
+// inner product, which is negated to the dot distance of Weaviate
+func (s *bigannSource) distances(cfg *Config, rows []int) [][]float32 {
+	if s.neighborsPath == "" {
+		return nil
+	}
+	gt, err := openBinFile(s.neighborsPath)
+	if err != nil {
+		log.Fatalf("Error opening neighbors: %v", err)
+	}
+	defer gt.Close()
+	if !gt.hasDistances() {
+		return nil
+	}
+	if cfg.DistanceMetric != "l2-squared" && cfg.DistanceMetric != "dot" {
+		log.Warnf("big-ann-benchmarks ground truth has no %s distances, recall is computed from ids only",
+			cfg.DistanceMetric)
+		return nil
+	}
+
+	distances, err := readRowRuns(rows, gt.rows, gt.distances)
+	if err != nil {
+		log.Fatalf("Error reading distances: %v", err)
+	}
+	if cfg.DistanceMetric == "dot" {
+		for _, row := range distances {
+			for j, d := range row {
+				row[j] = -d
+			}
+		}
+	}
+	return distances
+}
+
+func (s *bigannSource) categories(name string) []int {
+	log.Fatalf("big-ann-benchmarks datasets have no %s, filtering requires an hdf5 dataset", name)
+	return nil
+}
+
+func (s *bigannSource) Close() {
+	s.train.Close()
+}
@@ -0,0 +1,442 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate/entities/models"
+	weaviategrpc "github.com/weaviate/weaviate/grpc/generated/protocol/v1"
+)
+
+// Number of random nouns in a synthetic --documents document
+const syntheticDocumentWords = 50
+
+var bm25Command = &cobra.Command{
+	Use:   "bm25",
+	Short: "Benchmark BM25 keyword queries over gRPC",
+	Long: `Import a text corpus (a BEIR corpus.jsonl, a text file with one document per line or random nouns)
+and benchmark keyword-only BM25 queries against it. Queries are read from --queryTexts (a BEIR queries.jsonl
+or one query per line) or generated from random nouns. With BEIR --qrels recall, nDCG, MRR, MAP and precision at --limit are reported.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := globalConfig
+		cfg.Mode = "bm25"
+
+		if err := cfg.Validate(); err != nil {
+			fatal(err)
+		}
+
+		client := createClient(&cfg)
+
+		var corpusRows map[string]int
+		if !cfg.QueryOnly {
+			createKeywordSchema(&cfg, client)
+			corpusRows = loadKeywordCorpus(&cfg)
+		} else if cfg.QrelsFile != "" {
+			corpusRows = readCorpusRows(&cfg)
+		}
+
+		queries := loadKeywordQueries(&cfg, corpusRows)
+
+		var w io.Writer
+		if cfg.OutputFile == "" {
+			w = os.Stdout
+		} else {
+			f, err := os.Create(cfg.OutputFile)
+			if err != nil {
+				fatal(err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		result := benchmarkBM25(cfg, queries)
+		if cfg.OutputFormat == "json" {
+			result.WriteJSONTo(w)
+		} else {
+			result.WriteTextTo(w)
+		}
+
+		if cfg.OutputFile != "" {
+			infof("results succesfully written to %q", cfg.OutputFile)
+		}
+	},
+}
+
+func initBM25() {
+	rootCmd.AddCommand(bm25Command)
+	addQueryLoadFlags(bm25Command)
+
+	bm25Command.PersistentFlags().StringVar(&globalConfig.CorpusFile,
+		"corpus", "", "Corpus to import, a BEIR corpus.jsonl or a text file with one document per line")
+	bm25Command.PersistentFlags().IntVar(&globalConfig.Documents,
+		"documents", 0, "Import this many documents of random nouns instead of a corpus")
+	bm25Command.PersistentFlags().StringVar(&globalConfig.QueryTextsFile,
+		"queryTexts", "", "Keyword queries, a BEIR queries.jsonl or a text file with one query per line (default random nouns)")
+	bm25Command.PersistentFlags().IntVar(&globalConfig.Queries,
+		"randomQueries", 1000, "Number of random noun queries if no queryTexts are given")
+	bm25Command.PersistentFlags().StringVar(&globalConfig.QrelsFile,
+		"qrels", "", "BEIR qrels .tsv with the graded relevance of corpus documents, enables recall, nDCG, MRR, MAP and precision")
+	bm25Command.PersistentFlags().StringSliceVar(&globalConfig.QueryProperties,
+		"queryProperties", nil, "Properties searched by the queries (default all text properties)")
+	bm25Command.PersistentFlags().StringVarP(&globalConfig.ClassName,
+		"className", "c", "Document", "Class name for testing")
+	bm25Command.PersistentFlags().BoolVarP(&globalConfig.QueryOnly,
+		"query", "q", false, "Do not import data and only run query tests")
+	bm25Command.PersistentFlags().IntVar(&globalConfig.Shards,
+		"shards", 1, "Set number of Weaviate shards")
+	bm25Command.PersistentFlags().IntVarP(&globalConfig.BatchSize,
+		"batchSize", "b", 1000, "Batch size for insert operations")
+	bm25Command.PersistentFlags().IntVar(&globalConfig.ImportParallel,
+		"importParallel", 8, "Number of parallel workers sending import batches")
+	bm25Command.PersistentFlags().IntVarP(&globalConfig.Parallel,
+		"parallel", "p", 8, "Set the number of parallel threads which send queries")
+	bm25Command.PersistentFlags().IntVarP(&globalConfig.Limit,
+		"limit", "l", 10, "Set the query limit / k (default 10)")
+	bm25Command.PersistentFlags().StringVarP(&globalConfig.API,
+		"api", "a", "grpc", "The API to use on benchmarks")
+	bm25Command.PersistentFlags().StringVarP(&globalConfig.Origin,
+		"grpcOrigin", "u", "localhost:50051", "The gRPC origin that Weaviate is running at")
+	bm25Command.PersistentFlags().StringVar(&globalConfig.HttpOrigin,
+		"httpOrigin", "localhost:8080", "The http origin for Weaviate (only used if grpc enabled)")
+	bm25Command.PersistentFlags().StringVar(&globalConfig.HttpScheme,
+		"httpScheme", "http", "The http scheme (http or https)")
+	bm25Command.PersistentFlags().StringVarP(&globalConfig.OutputFormat,
+		"format", "f", "text", "Output format, one of [text, json]")
+	bm25Command.PersistentFlags().StringVarP(&globalConfig.OutputFile,
+		"output", "o", "", "Filename for an output file. If none provided, output to stdout only")
+}
+
+// A document of a BEIR corpus.jsonl, documents of plain text corpora use
+// their line number as id
+type corpusDocument struct {
+	ID    string `json:"_id"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// A query of a BEIR queries.jsonl, queries of plain text files use their
+// line number as id
+type keywordQuery struct {
+	ID   string `json:"_id"`
+	Text string `json:"text"`
+}
+
+// Re/create a class with title and text properties and without vectors
+func createKeywordSchema(cfg *Config, client *weaviate.Client) {
+	err := client.Schema().ClassDeleter().WithClassName(cfg.ClassName).Do(context.Background())
+	if err != nil {
+		log.Fatalf("Error deleting class: %v", err)
+	}
+
+	classObj := &models.Class{
+		Class:       cfg.ClassName,
+		Description: fmt.Sprintf("Created by the Weaviate Benchmarker at %s", time.Now().String()),
+		Vectorizer:  "none",
+		Properties: []*models.Property{
+			{Name: "title", DataType: []string{"text"}},
+			{Name: "text", DataType: []string{"text"}},
+		},
+	}
+	if cfg.Shards > 1 {
+		classObj.ShardingConfig = map[string]interface{}{
+			"desiredCount": cfg.Shards,
+		}
+	}
+
+	err = client.Schema().ClassCreator().WithClass(classObj).Do(context.Background())
+	if err != nil {
+		log.Fatalf("Error creating class: %v", err)
+	}
+	log.Printf("Created class %s", cfg.ClassName)
+}
+
+// Import the corpus or synthetic documents, returns the row of every corpus
+// document id which is used as its uuid
+func loadKeywordCorpus(cfg *Config) map[string]int {
+	setPhase("import", 0)
+	startTime := time.Now()
+	stats := newImportStats()
+	corpusRows := map[string]int{}
+
+	chunks := make(chan Batch, max(10, cfg.ImportParallel))
+	go func() {
+		defer close(chunks)
+
+		chunk := Batch{}
+		emit := func(row int, doc corpusDocument) {
+			corpusRows[doc.ID] = row
+			properties := map[string]interface{}{"text": doc.Text}
+			if doc.Title != "" {
+				properties["title"] = doc.Title
+			}
+			chunk.Properties = append(chunk.Properties, properties)
+			if len(chunk.Properties) == cfg.BatchSize {
+				chunks <- chunk
+				chunk = Batch{Offset: row + 1}
+			}
+		}
+
+		if cfg.CorpusFile != "" {
+			if err := readCorpus(cfg.CorpusFile, emit); err != nil {
+				log.Fatalf("Error reading corpus: %v", err)
+			}
+		} else {
+			for row := 0; row < cfg.Documents; row++ {
+				emit(row, corpusDocument{ID: strconv.Itoa(row), Text: randomSearchString(syntheticDocumentWords)})
+			}
+		}
+		if len(chunk.Properties) > 0 {
+			chunks <- chunk
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.ImportParallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			grpcConn := dialImport(cfg, stats)
+			defer grpcConn.Close()
+			grpcClient := weaviategrpc.NewWeaviateClient(grpcConn)
+
+			for chunk := range chunks {
+				writeChunk(&chunk, &grpcClient, cfg, stats)
+			}
+		}()
+	}
+	wg.Wait()
+
+	log.WithFields(log.Fields{"documents": len(corpusRows),
+		"duration": time.Since(startTime)}).Printf("Total load time\n")
+	logImportResults(stats.results())
+	return corpusRows
+}
+
+// The row of every corpus document id of an already imported corpus
+func readCorpusRows(cfg *Config) map[string]int {
+	corpusRows := map[string]int{}
+	err := readCorpus(cfg.CorpusFile, func(row int, doc corpusDocument) {
+		corpusRows[doc.ID] = row
+	})
+	if err != nil {
+		log.Fatalf("Error reading corpus: %v", err)
+	}
+	return corpusRows
+}
+
+// Stream the documents of a BEIR corpus.jsonl or a text file with one
+// document per line
+func readCorpus(path string, fn func(row int, doc corpusDocument)) error {
+	jsonl := strings.HasSuffix(strings.ToLower(path), ".jsonl")
+	return scanLines(path, func(row int, line string) error {
+		if !jsonl {
+			fn(row, corpusDocument{ID: strconv.Itoa(row), Text: line})
+			return nil
+		}
+
+		var doc corpusDocument
+		if err := json.Unmarshal([]byte(line), &doc); err != nil {
+			return fmt.Errorf("line %d: %w", row+1, err)
+		}
+		fn(row, doc)
+		return nil
+	})
+}
+
+// Call fn for every line of a text file
+func scanLines(path string, fn func(row int, line string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for row := 0; scanner.Scan(); row++ {
+		if err := fn(row, scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Read the queries of a BEIR queries.jsonl or a text file with one query per
+// line
+func readKeywordQueries(path string) ([]keywordQuery, error) {
+	jsonl := strings.HasSuffix(strings.ToLower(path), ".jsonl")
+	var queries []keywordQuery
+	err := scanLines(path, func(row int, line string) error {
+		if !jsonl {
+			queries = append(queries, keywordQuery{ID: strconv.Itoa(row), Text: line})
+			return nil
+		}
+
+		var query keywordQuery
+		if err := json.Unmarshal([]byte(line), &query); err != nil {
+			return fmt.Errorf("line %d: %w", row+1, err)
+		}
+		queries = append(queries, query)
+		return nil
+	})
+	return queries, err
+}
+
+// Read a BEIR qrels .tsv with a header and query-id, corpus-id, score rows
+// into the graded relevance of every query id and corpus id
+func readQrels(path string) (map[string]map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comma = '\t'
+	r.FieldsPerRecord = 3
+	r.ReuseRecord = true
+
+	qrels := map[string]map[string]int{}
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			return qrels, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		grade, err := strconv.Atoi(record[2])
+		if err != nil {
+			if line == 1 {
+				// header
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid score %q", line, record[2])
+		}
+		if qrels[record[0]] == nil {
+			qrels[record[0]] = map[string]int{}
+		}
+		qrels[record[0]][record[1]] = grade
+	}
+}
+
+// Read BEIR qrels of a vector dataset, the query ids are test rows and the
+// corpus ids train rows. Returns the graded relevance of the first count test
+// rows, nil for rows without judgments.
+func readRowQrels(path string, count int) ([]map[int]int, error) {
+	qrels, err := readQrels(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[int]int, count)
+	for queryID, judged := range qrels {
+		row, err := strconv.Atoi(queryID)
+		if err != nil {
+			return nil, fmt.Errorf("query id %q is not a test row", queryID)
+		}
+		if row < 0 || row >= count {
+			continue
+		}
+		rows[row] = make(map[int]int, len(judged))
+		for corpusID, grade := range judged {
+			trainRow, err := strconv.Atoi(corpusID)
+			if err != nil {
+				return nil, fmt.Errorf("corpus id %q is not a train row", corpusID)
+			}
+			rows[row][trainRow] = grade
+		}
+	}
+	return rows, nil
+}
+
+// The queries to benchmark with their relevance judgments by corpus row if
+// qrels are given, queries without judgments are skipped like in BEIR
+func loadKeywordQueries(cfg *Config, corpusRows map[string]int) []QueryWithNeighbors {
+	var texts []keywordQuery
+	if cfg.QueryTextsFile != "" {
+		var err error
+		texts, err = readKeywordQueries(cfg.QueryTextsFile)
+		if err != nil {
+			log.Fatalf("Error reading query texts: %v", err)
+		}
+	} else {
+		for i := 0; i < cfg.Queries; i++ {
+			texts = append(texts, keywordQuery{ID: strconv.Itoa(i), Text: randomSearchString(rand.Intn(3) + 1)})
+		}
+	}
+
+	var qrels map[string]map[string]int
+	if cfg.QrelsFile != "" {
+		var err error
+		qrels, err = readQrels(cfg.QrelsFile)
+		if err != nil {
+			log.Fatalf("Error reading qrels: %v", err)
+		}
+	}
+
+	queries := make([]QueryWithNeighbors, 0, len(texts))
+	for _, text := range texts {
+		query := QueryWithNeighbors{Query: bm25QueryGrpc(cfg, text.Text)}
+		if qrels != nil {
+			judged, ok := qrels[text.ID]
+			if !ok {
+				continue
+			}
+			query.Qrels = make(map[int]int, len(judged))
+			for id, grade := range judged {
+				if row, ok := corpusRows[id]; ok {
+					query.Qrels[row] = grade
+				}
+			}
+		}
+		queries = append(queries, query)
+	}
+	if len(queries) == 0 {
+		log.Fatalf("No queries to run, %d queries read", len(texts))
+	}
+
+	log.WithFields(log.Fields{"queries": len(queries), "read": len(texts),
+		"qrels": qrels != nil}).Info("Loaded keyword queries")
+	return queries
+}
+
+func bm25QueryGrpc(cfg *Config, text string) []byte {
+	return marshalSearchRequest(&weaviategrpc.SearchRequest{
+		Collection: cfg.ClassName,
+		Limit:      uint32(cfg.Limit),
+		Bm25Search: &weaviategrpc.BM25{
+			Query:      text,
+			Properties: cfg.QueryProperties,
+		},
+		Metadata: &weaviategrpc.MetadataRequest{
+			Uuid:  true,
+			Score: true,
+		},
+	})
+}
+
+func benchmarkBM25(cfg Config, queries []QueryWithNeighbors) Results {
+	cfg.Queries = len(queries)
+
+	i := 0
+	return benchmark(cfg, func(className string) QueryWithNeighbors {
+		defer func() { i = (i + 1) % len(queries) }()
+		return queries[i]
+	})
+}
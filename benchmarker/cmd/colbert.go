@@ -88,10 +88,6 @@ func loadHdf5StreamingColbert(dataset *hdf5.Dataset, chunks chan<- Batch, cfg *C
 			chunkData[j] = data
 		}
 
-		if (i+batchRows)%10000 == 0 {
-			log.Printf("Imported %d/%d rows", i+batchRows, rows)
-		}
-
 		filter := []int{}
 		if len(filters) > 0 {
 			filter = filters[i : i+batchRows]
@@ -105,7 +101,9 @@ func loadHdf5StreamingColbert(dataset *hdf5.Dataset, chunks chan<- Batch, cfg *C
 	}
 }
 
-func loadHdf5Colbert(file *hdf5.File, name string, dimensions int) [][]float32 {
+// Read the multi-vectors of a variable length dataset, only the selected rows
+// or all of them if rows is nil
+func loadHdf5Colbert(file *hdf5.File, name string, dimensions int, rows []int) [][]float32 {
 
 	var result [][]float32
 
@@ -122,7 +120,14 @@ func loadHdf5Colbert(file *hdf5.File, name string, dimensions int) [][]float32 {
 	}
 	log.Infof("Number of vectors: %v", fileDims[0])
 
-	result = make([][]float32, fileDims[0])
+	count := int(fileDims[0])
+	if err := checkQueryRows(rows, count); err != nil {
+		log.Fatalf("Error reading %s dataset: %v", name, err)
+	}
+	if rows != nil {
+		count = len(rows)
+	}
+	result = make([][]float32, count)
 
 	// For variable-length arrays, we need to allocate a slice of hvl_t structs
 	type hvl_t struct {
@@ -138,8 +143,13 @@ func loadHdf5Colbert(file *hdf5.File, name string, dimensions int) [][]float32 {
 	}
 	defer memspace.Close()
 
-	// Iterate through all vectors
-	for i := uint(0); i < fileDims[0]; i++ {
+	// Iterate through the selected vectors
+	for n := 0; n < count; n++ {
+		i := uint(n)
+		if rows != nil {
+			i = uint(rows[n])
+		}
+
 		// Allocate memory for one hvl_t struct
 		vlen := make([]hvl_t, 1)
 
@@ -167,7 +177,7 @@ func loadHdf5Colbert(file *hdf5.File, name string, dimensions int) [][]float32 {
 		if length%dimensions != 0 {
 			log.Fatalf("Length %d is not a multiple of dimensions %d", length, dimensions)
 		}
-		result[i] = data
+		result[n] = data
 	}
 	return result
 }
@@ -185,7 +195,7 @@ var colbertCmd = &cobra.Command{
 		}
 		defer file.Close()
 
-		res := loadHdf5Colbert(file, "train", cfg.MultiVectorDimensions)
+		res := loadHdf5Colbert(file, "train", cfg.MultiVectorDimensions, nil)
 
 		log.Infof("First vector:")
 		log.Infof("  Length: %d", len(res[0]))
@@ -67,9 +67,72 @@ type Config struct {
 	FlatSearchCutoff        int
 	FilterStrategy          string
 	AsyncReplicationEnabled bool
+	TargetQPS               float64
+	Arrival                 string
+	SweepBy                 string
+	SweepStart              float64
+	SweepFactor             float64
+	SweepSteps              int
+	SweepStepDuration       int
+	MaxP99                  float64
+	TimelineInterval        int
+	TimelineFormat          string
+	MetricsListen           string
+	MaxErrorRate            float64
+	ImportParallel          int
+	ImportConnections       int
+	TestVectorsFile         string
+	NeighborsFile           string
+	VectorColumn            string
+	PropertyColumns         []string
+	BFloat16                bool
+	Categories              int
+	CategoryDistribution    string
+	Seed                    int64
+	Generator               string
+	IntrinsicDimensions     int
+	Clusters                int
+	ClusterSpread           float64
+	Normalize               bool
+	TrainSize               int
+	TestSize                int
+	MaxQueries              int
+	SampleQueries           bool
+	RecallEpsilon           float64
+	QueryType               string
+	QueryTextsFile          string
+	QueryProperties         []string
+	Alpha                   float64
+	FusionType              string
+	CorpusFile              string
+	Documents               int
+	QrelsFile               string
+	FilterSpec              string
+	TrainPropertiesFile     string
+	TestPropertiesFile      string
+	Selectivities           string
+	// Flags of ground-truth and generate-dataset, bound apart from Limit,
+	// BatchSize, Parallel, DistanceMetric and Dimensions as their defaults
+	// differ from those of the query commands
+	GroundTruthK           int
+	GroundTruthBatchSize   int
+	GroundTruthParallel    int
+	GenerateDistanceMetric string
+	GenerateDimensions     int
+	// Set from Selectivities after validation
+	SelectivityCardinalities []int
 }
 
 func (c *Config) Validate() error {
+	// ground-truth and generate-dataset run offline, none of the connection
+	// settings apply
+	switch c.Mode {
+	case "ground-truth":
+		return c.validateGroundTruth()
+	case "generate-dataset":
+		return c.validateGenerateDataset()
+	}
+
 	if err := c.validateCommon(); err != nil {
 		return err
 	}
@@ -84,6 +147,12 @@ func (c *Config) Validate() error {
 		return c.validateDataset()
 	case "ann-benchmark":
 		return c.validateANN()
+	case "qps-sweep":
+		return c.validateQPSSweep()
+	case "bm25":
+		return c.validateBM25()
+	case "selectivity-sweep":
+		return c.validateSelectivitySweep()
 	default:
 		return errors.Errorf("unrecognized mode %q", c.Mode)
 	}
@@ -119,8 +188,27 @@ func (c *Config) validateCommon() error {
 		c.HttpAuth = httpAuth
 	}
 
+	if c.TargetQPS < 0 {
+		return errors.Errorf("targetQPS must not be negative")
+	}
+
+	if c.MaxErrorRate < 0 || c.MaxErrorRate > 1 {
+		return errors.Errorf("maxErrorRate must be between 0 and 1")
+	}
+
+	switch c.Arrival {
+	case "fixed", "":
+		c.Arrival = "fixed"
+	case "poisson":
+	default:
+		return errors.Errorf("unsupported arrival distribution %q, must be one of [fixed, poisson]",
+			c.Arrival)
+	}
+
 	if c.API == "grpc" && c.WhereFilter != "" {
-		return errors.Errorf("where parameter is not yet supported on grpc")
+		if _, err := parseWhereFilter(c.WhereFilter); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -169,5 +257,215 @@ func (c Config) validateANN() error {
 		return errors.Errorf("distance metric must be set")
 	}
 
+	if c.ImportParallel < 1 {
+		return errors.Errorf("importParallel must be at least 1")
+	}
+
+	if c.ImportConnections < 0 {
+		return errors.Errorf("importConnections must not be negative")
+	}
+
+	if c.TimelineInterval < 0 {
+		return errors.Errorf("timelineInterval must not be negative")
+	}
+
+	if c.TimelineFormat != "jsonl" && c.TimelineFormat != "csv" {
+		return errors.Errorf("unsupported timeline format %q, must be one of [jsonl, csv]",
+			c.TimelineFormat)
+	}
+
+	if len(c.PropertyColumns) > 0 && !strings.HasSuffix(strings.ToLower(c.BenchmarkFile), ".parquet") {
+		return errors.Errorf("propertyColumns is only supported for Parquet datasets")
+	}
+
+	return c.validateQueries()
+}
+
+func (c Config) validateQueries() error {
+	if c.MaxQueries < 0 {
+		return errors.Errorf("maxQueries must not be negative")
+	}
+
+	if c.SampleQueries && c.MaxQueries == 0 {
+		return errors.Errorf("sampleQueries requires maxQueries to be set")
+	}
+
+	if c.QrelsFile != "" && c.SampleQueries {
+		return errors.Errorf("qrels are not supported with sampleQueries")
+	}
+
+	if c.RecallEpsilon < 0 {
+		return errors.Errorf("recallEpsilon must not be negative")
+	}
+
+	switch c.QueryType {
+	case "nearVector", "":
+	case "hybrid":
+		if c.QueryTextsFile == "" {
+			return errors.Errorf("hybrid queries require queryTexts to be set")
+		}
+	default:
+		return errors.Errorf("unsupported query type %q, must be one of [nearVector, hybrid]", c.QueryType)
+	}
+
+	if c.Alpha < 0 || c.Alpha > 1 {
+		return errors.Errorf("alpha must be between 0 and 1")
+	}
+
+	if c.FusionType != "relativeScore" && c.FusionType != "ranked" {
+		return errors.Errorf("unsupported fusion type %q, must be one of [relativeScore, ranked]", c.FusionType)
+	}
+
+	return nil
+}
+
+func (c Config) validateGroundTruth() error {
+	if c.BenchmarkFile == "" {
+		return errors.Errorf("a vector benchmark file must be provided")
+	}
+
+	if _, err := newDistanceFunc(c.DistanceMetric); err != nil {
+		return err
+	}
+
+	if c.Limit < 1 || c.Parallel < 1 || c.BatchSize < 1 {
+		return errors.Errorf("limit, parallel and batchSize must be at least 1")
+	}
+
+	if c.MultiVectorDimensions > 0 {
+		return errors.Errorf("ground truth of multi-vector datasets is not supported")
+	}
+
+	if c.Categories < 0 {
+		return errors.Errorf("categories must not be negative")
+	}
+
+	if c.CategoryDistribution != "uniform" && c.CategoryDistribution != "normal" {
+		return errors.Errorf("unsupported category distribution %q, must be one of [uniform, normal]",
+			c.CategoryDistribution)
+	}
+
+	if c.OutputFile == "" && !isHdf5Path(c.BenchmarkFile) {
+		return errors.Errorf("output must be set, ground truth can only be added to hdf5 datasets")
+	}
+
+	return nil
+}
+
+func (c Config) validateGenerateDataset() error {
+	if c.OutputFile == "" {
+		return errors.Errorf("an output file must be provided")
+	}
+
+	if c.Generator != "gaussian-mixture" && c.Generator != "clustered" {
+		return errors.Errorf("unsupported generator %q, must be one of [gaussian-mixture, clustered]", c.Generator)
+	}
+
+	if _, err := newDistanceFunc(c.DistanceMetric); err != nil {
+		return err
+	}
+
+	if c.Dimensions < 1 || c.Clusters < 1 || c.TrainSize < 1 || c.TestSize < 1 {
+		return errors.Errorf("dimensions, clusters, trainSize and testSize must be at least 1")
+	}
+
+	if c.IntrinsicDimensions < 0 || c.IntrinsicDimensions > c.Dimensions {
+		return errors.Errorf("intrinsicDimensions must be between 0 and dimensions")
+	}
+
+	if c.ClusterSpread < 0 {
+		return errors.Errorf("clusterSpread must not be negative")
+	}
+
+	if c.Limit < 1 || c.Limit > c.TrainSize || c.Parallel < 1 || c.BatchSize < 1 {
+		return errors.Errorf("limit must be between 1 and trainSize, parallel and batchSize at least 1")
+	}
+
 	return nil
 }
+
+func (c Config) validateQPSSweep() error {
+	if c.BenchmarkFile == "" {
+		return errors.Errorf("a vector benchmark file must be provided")
+	}
+
+	if c.API != "grpc" {
+		return errors.Errorf("only grpc is supported for qps-sweep")
+	}
+
+	switch c.SweepBy {
+	case "qps", "parallel":
+	default:
+		return errors.Errorf("unsupported sweepBy %q, must be one of [qps, parallel]", c.SweepBy)
+	}
+
+	if c.SweepStart <= 0 || c.SweepFactor <= 1 {
+		return errors.Errorf("sweepStart must be positive and sweepFactor greater than 1")
+	}
+
+	if c.SweepSteps <= 0 || c.SweepStepDuration <= 0 {
+		return errors.Errorf("sweepSteps and sweepStepDuration must be positive")
+	}
+
+	if c.MaxP99 <= 0 {
+		return errors.Errorf("maxP99 must be positive")
+	}
+
+	return c.validateQueries()
+}
+
+func (c Config) validateBM25() error {
+	if c.API != "grpc" {
+		return errors.Errorf("only grpc is supported for bm25")
+	}
+
+	if c.CorpusFile != "" && c.Documents > 0 {
+		return errors.Errorf("corpus and documents are mutually exclusive")
+	}
+
+	if !c.QueryOnly && c.CorpusFile == "" && c.Documents < 1 {
+		return errors.Errorf("a corpus or a number of documents must be provided")
+	}
+
+	if c.QrelsFile != "" && (c.CorpusFile == "" || c.QueryTextsFile == "") {
+		return errors.Errorf("qrels require the corpus and queryTexts they refer to")
+	}
+
+	if c.QueryTextsFile == "" && c.Queries < 1 {
+		return errors.Errorf("randomQueries must be at least 1")
+	}
+
+	if c.Limit < 1 || c.BatchSize < 1 || c.ImportParallel < 1 {
+		return errors.Errorf("limit, batchSize and importParallel must be at least 1")
+	}
+
+	return nil
+}
+
+func (c Config) validateSelectivitySweep() error {
+	if c.BenchmarkFile == "" {
+		return errors.Errorf("a vector benchmark file must be provided")
+	}
+
+	if c.API != "grpc" {
+		return errors.Errorf("only grpc is supported for selectivity-sweep")
+	}
+
+	if _, err := newDistanceFunc(c.DistanceMetric); err != nil {
+		return err
+	}
+
+	if _, err := parseSelectivities(c.Selectivities); err != nil {
+		return err
+	}
+
+	if c.Filter || c.FilterSpec != "" || c.QrelsFile != "" || c.MultiVectorDimensions > 0 {
+		return errors.Errorf("filter, filterSpec, qrels and multiVector are not supported for selectivity-sweep")
+	}
+
+	if c.Limit < 1 || c.Parallel < 1 || c.BatchSize < 1 || c.ImportParallel < 1 {
+		return errors.Errorf("limit, parallel, batchSize and importParallel must be at least 1")
+	}
+
+	return c.validateQueries()
+}
@@ -55,6 +55,7 @@ var datasetCmd = &cobra.Command{
 
 func initDataset() {
 	rootCmd.AddCommand(datasetCmd)
+	addQueryLoadFlags(datasetCmd)
 	datasetCmd.PersistentFlags().StringVarP(&globalConfig.QueriesFile,
 		"queries", "q", "", "Point to the queries file, (.json)")
 	datasetCmd.PersistentFlags().IntVarP(&globalConfig.Parallel,
@@ -64,7 +65,7 @@ func initDataset() {
 	datasetCmd.PersistentFlags().StringVarP(&globalConfig.ClassName,
 		"className", "c", "", "The Weaviate class to run the benchmark against")
 	datasetCmd.PersistentFlags().StringVarP(&globalConfig.WhereFilter,
-		"where", "w", "", "An entire where filter as a string")
+		"where", "w", "", "An entire where filter as a string, on grpc also as JSON or the path of a JSON file")
 	datasetCmd.PersistentFlags().StringVarP(&globalConfig.API,
 		"api", "a", "graphql", "The API to use on benchmarks")
 	datasetCmd.PersistentFlags().StringVarP(&globalConfig.Origin,
@@ -95,6 +96,7 @@ func parseVectorsFromFile(cfg Config) (Queries, error) {
 
 func benchmarkDataset(cfg Config, queries Queries) Results {
 	cfg.Queries = len(queries)
+	filters := grpcWhereFilter(&cfg)
 
 	i := 0
 	return benchmark(cfg, func(className string) QueryWithNeighbors {
@@ -115,7 +117,7 @@ func benchmarkDataset(cfg Config, queries Queries) Results {
 
 		if cfg.API == "grpc" {
 			return QueryWithNeighbors{
-				Query: nearVectorQueryGrpc(&cfg, queries[i], cfg.Tenant, -1),
+				Query: nearVectorQueryGrpc(&cfg, queries[i], cfg.Tenant, filters),
 			}
 		}
 
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/weaviate/hdf5"
+)
+
+// A benchmark dataset: the training vectors to import, the test vectors with
+// their ground truth neighbors and optional filter categories
+type datasetSource interface {
+	// Number of training vectors and their dimensions
+	trainExtent(cfg *Config) (uint, uint)
+	// Stream the training vectors to chunks in batches of cfg.BatchSize,
+	// startOffset and maxRecords are ignored if equal to 0
+	streamTrain(chunks chan<- Batch, cfg *Config, startOffset uint, maxRecords uint, filters []int)
+	// Number of test vectors, the query sample is drawn from these rows
+	testCount(cfg *Config) int
+	// The test vectors and their neighbors at the rows picked by queryRows
+	// from the test count, all of them if rows is nil. Only the selected
+	// rows are read.
+	testVectors(cfg *Config, rows []int) [][]float32
+	neighbors(cfg *Config, rows []int) [][]int
+	// Distances of the neighbors in the metric of cfg.DistanceMetric, nil if
+	// the dataset has none
+	distances(cfg *Config, rows []int) [][]float32
+	// Categories of the train_categories or test_categories dataset
+	categories(name string) []int
+	Close()
+}
+
+// Open the dataset in cfg.BenchmarkFile, the format is picked by extension.
+// An hdf5 --neighbors file replaces the ground truth of any format.
+func openDatasetSource(cfg *Config) datasetSource {
+	source := openDatasetFormat(cfg)
+	if cfg.NeighborsFile != "" && isHdf5Path(cfg.NeighborsFile) {
+		return &hdf5NeighborsSource{datasetSource: source, path: cfg.NeighborsFile}
+	}
+	return source
+}
+
+func isHdf5Path(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".hdf5", ".h5":
+		return true
+	default:
+		return false
+	}
+}
+
+// The test rows to benchmark in ascending order, nil for all of them. With
+// --maxQueries only the first rows are used, or a random sample of them with
+// --sampleQueries. The rows are computed once from the number of test
+// vectors and passed to the readers of the neighbors, filters and texts.
+func queryRows(cfg *Config, total int) []int {
+	if cfg.MaxQueries <= 0 || cfg.MaxQueries >= total {
+		return nil
+	}
+
+	var rows []int
+	if cfg.SampleQueries {
+		rows = rand.New(rand.NewSource(cfg.Seed)).Perm(total)[:cfg.MaxQueries]
+		slices.Sort(rows)
+	} else {
+		rows = make([]int, cfg.MaxQueries)
+		for i := range rows {
+			rows[i] = i
+		}
+	}
+	return rows
+}
+
+// Check that a file with total rows has all of the ascending rows, so a
+// shorter neighbors or filters file fails instead of pairing other rows
+func checkQueryRows(rows []int, total int) error {
+	if n := len(rows); n > 0 && rows[n-1] >= total {
+		return errors.Errorf("%d rows but the queries include row %d", total, rows[n-1])
+	}
+	return nil
+}
+
+// Read the rows at the ascending indices rows, or all total rows if rows is
+// nil, with one read per run of consecutive rows
+func readRowRuns[T any](rows []int, total int, read func(start, count int) ([]T, error)) ([]T, error) {
+	if rows == nil {
+		return read(0, total)
+	}
+	if err := checkQueryRows(rows, total); err != nil {
+		return nil, err
+	}
+
+	out := make([]T, 0, len(rows))
+	for i := 0; i < len(rows); {
+		j := i + 1
+		for j < len(rows) && rows[j] == rows[j-1]+1 {
+			j++
+		}
+		run, err := read(rows[i], j-i)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, run...)
+		i = j
+	}
+	return out, nil
+}
+
+// The values at rows, all of them if rows is nil
+func pickRows[T any](values []T, rows []int) []T {
+	if rows == nil {
+		return values
+	}
+	out := make([]T, len(rows))
+	for i, row := range rows {
+		out[i] = values[row]
+	}
+	return out
+}
+
+func openDatasetFormat(cfg *Config) datasetSource {
+	if binFileKind(cfg.BenchmarkFile) != "" {
+		return openBigannSource(cfg)
+	}
+
+	if info, err := os.Stat(cfg.BenchmarkFile); err == nil && info.IsDir() {
+		return openNpySource(cfg)
+	}
+
+	switch strings.ToLower(filepath.Ext(cfg.BenchmarkFile)) {
+	case ".fvecs", ".bvecs", ".ivecs":
+		return openTexmexSource(cfg)
+	case ".parquet":
+		return openParquetSource(cfg)
+	case ".npz":
+		return openNpySource(cfg)
+	default:
+		return openHdf5Source(cfg)
+	}
+}
+
+// An hdf5 file in the format of ann-benchmarks.com
+type hdf5Source struct {
+	file *hdf5.File
+}
+
+func openHdf5Source(cfg *Config) *hdf5Source {
+	file, err := hdf5.OpenFile(cfg.BenchmarkFile, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		log.Fatalf("Error opening file: %v\n", err)
+	}
+	return &hdf5Source{file: file}
+}
+
+func (s *hdf5Source) trainExtent(cfg *Config) (uint, uint) {
+	dataset, err := s.file.OpenDataset("train")
+	if err != nil {
+		log.Fatalf("Error opening dataset: %v", err)
+	}
+	defer dataset.Close()
+	extent, _, _ := dataset.Space().SimpleExtentDims()
+
+	if cfg.MultiVectorDimensions > 0 {
+		return extent[0], uint(cfg.MultiVectorDimensions)
+	}
+	return extent[0], extent[1]
+}
+
+func (s *hdf5Source) streamTrain(chunks chan<- Batch, cfg *Config, startOffset uint, maxRecords uint, filters []int) {
+	dataset, err := s.file.OpenDataset("train")
+	if err != nil {
+		log.Fatalf("Error opening dataset: %v", err)
+	}
+	defer dataset.Close()
+
+	if cfg.MultiVectorDimensions > 0 {
+		loadHdf5StreamingColbert(dataset, chunks, cfg, startOffset, maxRecords, filters)
+	} else {
+		loadHdf5Streaming(dataset, chunks, cfg, startOffset, maxRecords, filters)
+	}
+}
+
+func (s *hdf5Source) testCount(cfg *Config) int {
+	dataset, err := s.file.OpenDataset("test")
+	if err != nil {
+		log.Fatalf("Error opening dataset: %v", err)
+	}
+	defer dataset.Close()
+	extent, _, _ := dataset.Space().SimpleExtentDims()
+	return int(extent[0])
+}
+
+func (s *hdf5Source) testVectors(cfg *Config, rows []int) [][]float32 {
+	if cfg.MultiVectorDimensions > 0 {
+		return loadHdf5Colbert(s.file, "test", cfg.MultiVectorDimensions, rows)
+	}
+	return loadHdf5Float32(s.file, "test", cfg, rows)
+}
+
+func (s *hdf5Source) neighbors(cfg *Config, rows []int) [][]int {
+	return loadHdf5Neighbors(s.file, "neighbors", rows)
+}
+
+func (s *hdf5Source) distances(cfg *Config, rows []int) [][]float32 {
+	return loadHdf5Distances(s.file, "distances", cfg, rows)
+}
+
+func (s *hdf5Source) categories(name string) []int {
+	return loadHdf5Categories(s.file, name)
+}
+
+func (s *hdf5Source) Close() {
+	s.file.Close()
+}
+
+// Ground truth in a separate hdf5 file, e.g. computed with ground-truth --output
+type hdf5NeighborsSource struct {
+	datasetSource
+	path string
+}
+
+func (s *hdf5NeighborsSource) neighbors(cfg *Config, rows []int) [][]int {
+	file, err := hdf5.OpenFile(s.path, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		log.Fatalf("Error opening neighbors file: %v", err)
+	}
+	defer file.Close()
+	return loadHdf5Neighbors(file, "neighbors", rows)
+}
+
+func (s *hdf5NeighborsSource) distances(cfg *Config, rows []int) [][]float32 {
+	file, err := hdf5.OpenFile(s.path, hdf5.F_ACC_RDONLY)
+	if err != nil {
+		log.Fatalf("Error opening neighbors file: %v", err)
+	}
+	defer file.Close()
+	return loadHdf5Distances(file, "distances", cfg, rows)
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// A distance between two vectors, smaller is closer
+type distanceFunc func(a, b []float32) float32
+
+// The distance function of a Weaviate distance metric, with the same
+// definitions as the server so locally computed neighbors match its results
+func newDistanceFunc(metric string) (distanceFunc, error) {
+	switch metric {
+	case "cosine":
+		return cosineDistance, nil
+	case "dot":
+		return dotDistance, nil
+	case "l2-squared":
+		return l2SquaredDistance, nil
+	case "hamming":
+		return hammingDistance, nil
+	case "manhattan":
+		return manhattanDistance, nil
+	default:
+		return nil, errors.Errorf("unsupported distance %q, must be one of [cosine, dot, l2-squared, hamming, manhattan]", metric)
+	}
+}
+
+func cosineDistance(a, b []float32) float32 {
+	var dot, normA, normB float32
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 1
+	}
+	return 1 - dot/float32(math.Sqrt(float64(normA))*math.Sqrt(float64(normB)))
+}
+
+func dotDistance(a, b []float32) float32 {
+	var dot float32
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return -dot
+}
+
+func l2SquaredDistance(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		diff := a[i] - b[i]
+		sum += diff * diff
+	}
+	return sum
+}
+
+func hammingDistance(a, b []float32) float32 {
+	var differing float32
+	for i := range a {
+		if a[i] != b[i] {
+			differing++
+		}
+	}
+	return differing
+}
+
+func manhattanDistance(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += float32(math.Abs(float64(a[i] - b[i])))
+	}
+	return sum
+}
+
+type neighbor struct {
+	id       int
+	distance float32
+}
+
+// The k closest ids seen so far, a max-heap so the farthest one is replaced
+// first
+type topK struct {
+	k         int
+	neighbors []neighbor
+}
+
+func newTopK(k int) *topK {
+	return &topK{k: k, neighbors: make([]neighbor, 0, k)}
+}
+
+func (t *topK) Len() int { return len(t.neighbors) }
+
+func (t *topK) Less(i, j int) bool { return closer(t.neighbors[j], t.neighbors[i]) }
+
+func (t *topK) Swap(i, j int) { t.neighbors[i], t.neighbors[j] = t.neighbors[j], t.neighbors[i] }
+
+func (t *topK) Push(x interface{}) { t.neighbors = append(t.neighbors, x.(neighbor)) }
+
+func (t *topK) Pop() interface{} {
+	last := t.neighbors[len(t.neighbors)-1]
+	t.neighbors = t.neighbors[:len(t.neighbors)-1]
+	return last
+}
+
+// Ties are broken by id so results do not depend on the scan order
+func closer(a, b neighbor) bool {
+	if a.distance != b.distance {
+		return a.distance < b.distance
+	}
+	return a.id < b.id
+}
+
+func (t *topK) insert(id int, distance float32) {
+	n := neighbor{id: id, distance: distance}
+	if len(t.neighbors) < t.k {
+		heap.Push(t, n)
+		return
+	}
+	if closer(n, t.neighbors[0]) {
+		t.neighbors[0] = n
+		heap.Fix(t, 0)
+	}
+}
+
+// The ids and distances ordered from the closest
+func (t *topK) sorted() ([]int, []float32) {
+	sorted := slices.Clone(t.neighbors)
+	sort.Slice(sorted, func(i, j int) bool { return closer(sorted[i], sorted[j]) })
+
+	ids := make([]int, len(sorted))
+	distances := make([]float32, len(sorted))
+	for i, n := range sorted {
+		ids[i], distances[i] = n.id, n.distance
+	}
+	return ids, distances
+}
@@ -0,0 +1,453 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	weaviategrpc "github.com/weaviate/weaviate/grpc/generated/protocol/v1"
+)
+
+// A where filter in the JSON format of the Weaviate REST API, e.g.
+//
+//	{"operator": "And", "operands": [
+//	  {"path": ["price"], "operator": "LessThan", "valueNumber": "{{maxPrice}}"},
+//	  {"path": ["tags"], "operator": "ContainsAny", "valueTextArray": ["a", "b"]}]}
+//
+// The filters dataset of generate-filtered-beir-dataset.py names the operator
+// "operation", which is accepted as well.
+type whereFilter struct {
+	Operator          string        `json:"operator"`
+	Operation         string        `json:"operation"`
+	Operands          []whereFilter `json:"operands"`
+	Path              []string      `json:"path"`
+	ValueText         *string       `json:"valueText"`
+	ValueString       *string       `json:"valueString"`
+	ValueDate         *string       `json:"valueDate"`
+	ValueInt          *int64        `json:"valueInt"`
+	ValueNumber       *float64      `json:"valueNumber"`
+	ValueBoolean      *bool         `json:"valueBoolean"`
+	ValueTextArray    []string      `json:"valueTextArray"`
+	ValueStringArray  []string      `json:"valueStringArray"`
+	ValueDateArray    []string      `json:"valueDateArray"`
+	ValueIntArray     []int64       `json:"valueIntArray"`
+	ValueNumberArray  []float64     `json:"valueNumberArray"`
+	ValueBooleanArray []bool        `json:"valueBooleanArray"`
+}
+
+var filterOperators = map[string]weaviategrpc.Filters_Operator{
+	"And":              weaviategrpc.Filters_OPERATOR_AND,
+	"Or":               weaviategrpc.Filters_OPERATOR_OR,
+	"Equal":            weaviategrpc.Filters_OPERATOR_EQUAL,
+	"NotEqual":         weaviategrpc.Filters_OPERATOR_NOT_EQUAL,
+	"GreaterThan":      weaviategrpc.Filters_OPERATOR_GREATER_THAN,
+	"GreaterThanEqual": weaviategrpc.Filters_OPERATOR_GREATER_THAN_EQUAL,
+	"LessThan":         weaviategrpc.Filters_OPERATOR_LESS_THAN,
+	"LessThanEqual":    weaviategrpc.Filters_OPERATOR_LESS_THAN_EQUAL,
+	"Like":             weaviategrpc.Filters_OPERATOR_LIKE,
+	"IsNull":           weaviategrpc.Filters_OPERATOR_IS_NULL,
+	"ContainsAny":      weaviategrpc.Filters_OPERATOR_CONTAINS_ANY,
+	"ContainsAll":      weaviategrpc.Filters_OPERATOR_CONTAINS_ALL,
+}
+
+// Convert the filter to its gRPC form, dates are sent as text and parsed by
+// Weaviate according to the property type
+func (f whereFilter) grpc() (*weaviategrpc.Filters, error) {
+	name := f.Operator
+	if name == "" {
+		name = f.Operation
+	}
+	operator, ok := filterOperators[name]
+	if !ok {
+		return nil, errors.Errorf("unsupported filter operator %q", name)
+	}
+	out := &weaviategrpc.Filters{Operator: operator}
+
+	if operator == weaviategrpc.Filters_OPERATOR_AND || operator == weaviategrpc.Filters_OPERATOR_OR {
+		if len(f.Operands) == 0 {
+			return nil, errors.Errorf("%s filter without operands", name)
+		}
+		for _, operand := range f.Operands {
+			filters, err := operand.grpc()
+			if err != nil {
+				return nil, err
+			}
+			out.Filters = append(out.Filters, filters)
+		}
+		return out, nil
+	}
+
+	if len(f.Path) == 0 {
+		return nil, errors.Errorf("%s filter without path", name)
+	}
+	out.On = f.Path
+
+	values := 0
+	set := func(present bool, value func()) {
+		if present {
+			value()
+			values++
+		}
+	}
+	set(f.ValueText != nil, func() { out.TestValue = &weaviategrpc.Filters_ValueText{ValueText: *f.ValueText} })
+	set(f.ValueString != nil, func() { out.TestValue = &weaviategrpc.Filters_ValueText{ValueText: *f.ValueString} })
+	set(f.ValueDate != nil, func() { out.TestValue = &weaviategrpc.Filters_ValueText{ValueText: *f.ValueDate} })
+	set(f.ValueInt != nil, func() { out.TestValue = &weaviategrpc.Filters_ValueInt{ValueInt: *f.ValueInt} })
+	set(f.ValueNumber != nil, func() { out.TestValue = &weaviategrpc.Filters_ValueNumber{ValueNumber: *f.ValueNumber} })
+	set(f.ValueBoolean != nil, func() { out.TestValue = &weaviategrpc.Filters_ValueBoolean{ValueBoolean: *f.ValueBoolean} })
+	set(f.ValueTextArray != nil, func() {
+		out.TestValue = &weaviategrpc.Filters_ValueTextArray{ValueTextArray: &weaviategrpc.TextArray{Values: f.ValueTextArray}}
+	})
+	set(f.ValueStringArray != nil, func() {
+		out.TestValue = &weaviategrpc.Filters_ValueTextArray{ValueTextArray: &weaviategrpc.TextArray{Values: f.ValueStringArray}}
+	})
+	set(f.ValueDateArray != nil, func() {
+		out.TestValue = &weaviategrpc.Filters_ValueTextArray{ValueTextArray: &weaviategrpc.TextArray{Values: f.ValueDateArray}}
+	})
+	set(f.ValueIntArray != nil, func() {
+		out.TestValue = &weaviategrpc.Filters_ValueIntArray{ValueIntArray: &weaviategrpc.IntArray{Values: f.ValueIntArray}}
+	})
+	set(f.ValueNumberArray != nil, func() {
+		out.TestValue = &weaviategrpc.Filters_ValueNumberArray{ValueNumberArray: &weaviategrpc.NumberArray{Values: f.ValueNumberArray}}
+	})
+	set(f.ValueBooleanArray != nil, func() {
+		out.TestValue = &weaviategrpc.Filters_ValueBooleanArray{ValueBooleanArray: &weaviategrpc.BooleanArray{Values: f.ValueBooleanArray}}
+	})
+	if values != 1 {
+		return nil, errors.Errorf("%s filter on %v needs exactly one value, got %d", name, f.Path, values)
+	}
+	return out, nil
+}
+
+// Placeholders like "{{price}}" stand for the value of the test row
+var filterPlaceholder = regexp.MustCompile(`"\{\{([^{}"]+)\}\}"`)
+
+// A where filter template whose placeholders are filled in per query
+type filterSpec struct {
+	template []byte
+}
+
+// Read a filter spec file and check that it is a valid filter
+func readFilterSpec(path string) (*filterSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseFilterSpec(data)
+}
+
+func parseFilterSpec(data []byte) (*filterSpec, error) {
+	// Placeholders are replaced by null to check the spec up front, the
+	// values are only checked once they are filled in
+	decoder := json.NewDecoder(bytes.NewReader(filterPlaceholder.ReplaceAll(data, []byte("null"))))
+	decoder.DisallowUnknownFields()
+	var filter whereFilter
+	if err := decoder.Decode(&filter); err != nil {
+		return nil, errors.Wrap(err, "invalid filter spec")
+	}
+	return &filterSpec{template: data}, nil
+}
+
+// The placeholders used by the spec
+func (s *filterSpec) placeholders() []string {
+	var names []string
+	for _, match := range filterPlaceholder.FindAllSubmatch(s.template, -1) {
+		names = append(names, string(match[1]))
+	}
+	return names
+}
+
+// The filter of a query with the placeholders replaced by the JSON encoded
+// values of the test row, so numbers stay numbers and lists become arrays
+func (s *filterSpec) filters(values map[string]interface{}) (*weaviategrpc.Filters, error) {
+	var missing error
+	resolved := filterPlaceholder.ReplaceAllFunc(s.template, func(match []byte) []byte {
+		name := string(filterPlaceholder.FindSubmatch(match)[1])
+		value, ok := values[name]
+		if !ok {
+			missing = errors.Errorf("no value for filter placeholder %q", name)
+			return match
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			missing = err
+			return match
+		}
+		return encoded
+	})
+	if missing != nil {
+		return nil, missing
+	}
+
+	var filter whereFilter
+	decoder := json.NewDecoder(bytes.NewReader(resolved))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&filter); err != nil {
+		return nil, errors.Wrap(err, "invalid filter")
+	}
+	return filter.grpc()
+}
+
+// Parse the --where filter for gRPC queries. It is either the GraphQL
+// argument as passed to the graphql API, e.g.
+//
+//	, where: {operator: Equal, path: [\"category\"], valueText: \"5\"}
+//
+// the same filter in JSON, or the path of a JSON file holding it.
+func parseWhereFilter(where string) (*weaviategrpc.Filters, error) {
+	where = strings.TrimSpace(where)
+	where = strings.TrimSpace(strings.TrimPrefix(where, ","))
+	if rest, ok := strings.CutPrefix(where, "where"); ok && strings.HasPrefix(strings.TrimSpace(rest), ":") {
+		where = strings.TrimSpace(strings.TrimSpace(rest)[1:])
+	}
+
+	data := []byte(where)
+	if !strings.HasPrefix(where, "{") {
+		var err error
+		if data, err = os.ReadFile(where); err != nil {
+			return nil, errors.Wrap(err, "where filter is neither an object nor a readable file")
+		}
+	}
+
+	// JSON is used as is, escaped quotes are part of its string values
+	filter, err := decodeWhereFilter(data)
+	if err != nil {
+		// The GraphQL argument is embedded in the JSON body of the query, so
+		// its quotes are escaped
+		data = bytes.ReplaceAll(data, []byte(`\"`), []byte(`"`))
+		if filter, err = decodeWhereFilter(graphQLToJSON(data)); err != nil {
+			return nil, errors.Wrap(err, "invalid where filter")
+		}
+	}
+	return filter.grpc()
+}
+
+func decodeWhereFilter(data []byte) (whereFilter, error) {
+	var filter whereFilter
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&filter)
+	return filter, err
+}
+
+// Turn a GraphQL input object into JSON by quoting its keys and enum values,
+// JSON passes through unchanged
+func graphQLToJSON(in []byte) []byte {
+	isName := func(c byte) bool {
+		return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+	}
+
+	var out []byte
+	for i := 0; i < len(in); {
+		c := in[i]
+		switch {
+		case c == '"':
+			// copy strings including their escapes
+			j := i + 1
+			for j < len(in) && in[j] != '"' {
+				if in[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(in))
+			out = append(out, in[i:j]...)
+			i = j
+		case c == '-' || ('0' <= c && c <= '9'):
+			// numbers, including exponents like 1e-3
+			j := i + 1
+			for j < len(in) && (isName(in[j]) || in[j] == '.' || in[j] == '+' || in[j] == '-') {
+				j++
+			}
+			out = append(out, in[i:j]...)
+			i = j
+		case isName(c):
+			j := i
+			for j < len(in) && isName(in[j]) {
+				j++
+			}
+			switch name := string(in[i:j]); name {
+			case "true", "false", "null":
+				out = append(out, name...)
+			default:
+				out = append(out, strconv.Quote(name)...)
+			}
+			i = j
+		default:
+			out = append(out, c)
+			i++
+		}
+	}
+	return out
+}
+
+// The --where filter of gRPC queries, nil without one
+func grpcWhereFilter(cfg *Config) *weaviategrpc.Filters {
+	if cfg.API != "grpc" || cfg.WhereFilter == "" {
+		return nil
+	}
+	filters, err := parseWhereFilter(cfg.WhereFilter)
+	if err != nil {
+		log.Fatalf("Error parsing where filter: %v", err)
+	}
+	return filters
+}
+
+// The filter of the --filter flag, the category of the test row has to match
+// the category of the train objects
+const categoryFilterSpec = `{"path": ["category"], "operator": "Equal", "valueText": "{{category}}"}`
+
+// Build the filter of every test row from --filterSpec, or the category
+// filter with --filter. Placeholders are filled from the --testProperties
+// rows and the test categories of the dataset, at the query rows picked by
+// loadQueries. Returns nil without filters.
+func loadTestFilters(source datasetSource, cfg *Config, rows []int, count int) []*weaviategrpc.Filters {
+	if cfg.FilterSpec == "" && !cfg.Filter {
+		return nil
+	}
+
+	spec, err := parseFilterSpec([]byte(categoryFilterSpec))
+	if cfg.FilterSpec != "" {
+		spec, err = readFilterSpec(cfg.FilterSpec)
+	}
+	if err != nil {
+		log.Fatalf("Error reading filter spec: %v", err)
+	}
+
+	values := make([]map[string]interface{}, count)
+	for i := range values {
+		values[i] = map[string]interface{}{}
+	}
+
+	if cfg.TestPropertiesFile != "" {
+		properties, err := readPropertyRows(cfg.TestPropertiesFile)
+		if err != nil {
+			log.Fatalf("Error reading test properties: %v", err)
+		}
+		if err := checkQueryRows(rows, len(properties)); err != nil {
+			log.Fatalf("Error reading test properties: %v", err)
+		}
+		properties = pickRows(properties, rows)
+		if len(properties) < count {
+			log.Fatalf("Found test properties for %d of %d test vectors", len(properties), count)
+		}
+		for i := range values {
+			for name, value := range properties[i] {
+				values[i][name] = value
+			}
+		}
+	}
+
+	if cfg.Filter {
+		categories := source.categories("test_categories")
+		if err := checkQueryRows(rows, len(categories)); err != nil {
+			log.Fatalf("Error reading test categories: %v", err)
+		}
+		categories = pickRows(categories, rows)
+		if len(categories) < count {
+			log.Fatalf("Found categories for %d of %d test vectors", len(categories), count)
+		}
+		for i := range values {
+			values[i]["category"] = strconv.Itoa(categories[i])
+		}
+	}
+
+	filters := make([]*weaviategrpc.Filters, count)
+	for i := range filters {
+		filters[i], err = spec.filters(values[i])
+		if err != nil {
+			log.Fatalf("Error building the filter of test vector %d: %v", i, err)
+		}
+	}
+
+	log.WithFields(log.Fields{"placeholders": spec.placeholders()}).Info("Built filters of the test vectors")
+	return filters
+}
+
+// Read a JSON lines file with the properties of one object per line
+func readPropertyRows(path string) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	err := scanLines(path, func(row int, line string) error {
+		properties, err := decodeProperties([]byte(line))
+		if err != nil {
+			return errors.Wrapf(err, "line %d", row+1)
+		}
+		rows = append(rows, properties)
+		return nil
+	})
+	return rows, err
+}
+
+// Decode a JSON object of properties, numbers are kept as json.Number so
+// integers stay integers when they are written to a filter or object
+func decodeProperties(data []byte) (map[string]interface{}, error) {
+	var properties map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&properties); err != nil {
+		return nil, err
+	}
+	return properties, nil
+}
+
+// Pass on the batches of in with the properties of their rows in a JSON lines
+// file added, the batches have to arrive in the order of their offsets
+func addTrainProperties(in <-chan Batch, out chan<- Batch, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	row := 0
+	next := func() (map[string]interface{}, error) {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, errors.Errorf("no properties for row %d", row)
+		}
+		row++
+		properties, err := decodeProperties(scanner.Bytes())
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", row)
+		}
+		return properties, nil
+	}
+
+	for chunk := range in {
+		if chunk.Offset < row {
+			return errors.Errorf("batch at row %d arrived after row %d", chunk.Offset, row)
+		}
+		for row < chunk.Offset {
+			if _, err := next(); err != nil {
+				return err
+			}
+		}
+
+		merged := make([]map[string]interface{}, chunk.len())
+		for i := range merged {
+			properties, err := next()
+			if err != nil {
+				return err
+			}
+			if chunk.Properties != nil {
+				for name, value := range chunk.Properties[i] {
+					properties[name] = value
+				}
+			}
+			merged[i] = properties
+		}
+		chunk.Properties = merged
+		out <- chunk
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Reset the flags of cmd that were not set on the command line to the
+// defaults cmd registered them with. The commands bind their flags to the
+// shared globalConfig, so without this a field starts from the default of
+// whichever command registered it last.
+func applyFlagDefaults(cmd *cobra.Command) error {
+	var err error
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed || err != nil {
+			return
+		}
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			var values []string
+			if def := strings.Trim(f.DefValue, "[]"); def != "" {
+				values = strings.Split(def, ",")
+			}
+			err = slice.Replace(values)
+			return
+		}
+		err = f.Value.Set(f.DefValue)
+	})
+	return err
+}
+
+// Register the flags of commands that send queries, the offered load and the
+// share of queries that may fail
+func addQueryLoadFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().Float64Var(&globalConfig.TargetQPS,
+		"targetQPS", 0, "Send queries open-loop at this rate instead of as fast as possible, latency is measured from the intended send time (default 0, closed loop)")
+	cmd.PersistentFlags().StringVar(&globalConfig.Arrival,
+		"arrival", "fixed", "Arrival distribution of queries when targetQPS is set (fixed or poisson)")
+	cmd.PersistentFlags().Float64Var(&globalConfig.MaxErrorRate,
+		"maxErrorRate", 0.05, "Abort the run once the share of failed queries passes this rate (0 to 1)")
+}
+
+// Register the flags that locate the train and test vectors of a dataset in
+// any of the formats supported by ann-benchmark
+func addDatasetFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&globalConfig.BenchmarkFile,
+		"vectors", "v", "", "Path to the hdf5 file, .npz archive or directory of .npy files, TEXMEX .fvecs/.bvecs, big-ann-benchmarks .fbin/.u8bin/.i8bin or .parquet base vectors")
+	cmd.PersistentFlags().StringVar(&globalConfig.TestVectorsFile,
+		"testVectors", "", "Path to the test vectors of a TEXMEX, big-ann-benchmarks or Parquet dataset (TEXMEX default <name>_query next to <name>_base)")
+	cmd.PersistentFlags().StringVar(&globalConfig.VectorColumn,
+		"vectorColumn", "vector", "Column of a Parquet dataset that holds the vectors")
+	cmd.PersistentFlags().BoolVar(&globalConfig.BFloat16,
+		"bfloat16", false, "Decode 2-byte hdf5 vectors (float or uint16) as bfloat16 instead of float16")
+}
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/weaviate/hdf5"
+)
+
+var generateDatasetCommand = &cobra.Command{
+	Use:   "generate-dataset",
+	Short: "Generate a clustered ann-benchmarks hdf5 dataset with exact ground truth",
+	Long: `Draw train and test vectors from a mixture of Gaussian clusters and write them as an hdf5 file in the
+format of ann-benchmarks.com, including the exact neighbors and distances of the test vectors.
+
+Clusters live in a space of --intrinsicDimensions that is randomly projected to --dimensions, so the data has
+a low intrinsic dimensionality like real embeddings. gaussian-mixture draws clusters of random size and spread,
+clustered draws clusters of equal size and spread. The train vectors are written in batches, the size of the
+dataset is only limited by disk space`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := globalConfig
+		cfg.Mode = "generate-dataset"
+		cfg.Limit = cfg.GroundTruthK
+		cfg.BatchSize = cfg.GroundTruthBatchSize
+		cfg.Parallel = cfg.GroundTruthParallel
+		cfg.DistanceMetric = cfg.GenerateDistanceMetric
+		cfg.Dimensions = cfg.GenerateDimensions
+
+		if err := cfg.Validate(); err != nil {
+			fatal(err)
+		}
+
+		distance, err := newDistanceFunc(cfg.DistanceMetric)
+		if err != nil {
+			fatal(err)
+		}
+
+		startTime := time.Now()
+		generator := newMixtureGenerator(&cfg)
+
+		test := make([][]float32, cfg.TestSize)
+		for i := range test {
+			test[i] = generator.vector()
+		}
+
+		if err := writeGeneratedDataset(&cfg, generator, test); err != nil {
+			log.Fatalf("Error writing dataset: %v", err)
+		}
+		log.WithFields(log.Fields{"train": cfg.TrainSize, "test": cfg.TestSize, "dimensions": cfg.Dimensions,
+			"duration": time.Since(startTime)}).Info("Wrote vectors, computing ground truth")
+
+		// Ground truth is computed from the written file so the train vectors
+		// never need to be in memory at once
+		gtCfg := cfg
+		gtCfg.BenchmarkFile = cfg.OutputFile
+		source := openHdf5Source(&gtCfg)
+		gt := &groundTruth{}
+		gt.neighbors, gt.distances = computeGroundTruth(source, &gtCfg, test, nil, nil, distance)
+		source.Close()
+
+		if err := writeHdf5GroundTruth(cfg.OutputFile, gt); err != nil {
+			log.Fatalf("Error writing ground truth: %v", err)
+		}
+
+		log.WithFields(log.Fields{"output": cfg.OutputFile, "duration": time.Since(startTime)}).
+			Info("Generated dataset")
+	},
+}
+
+func initGenerateDataset() {
+	rootCmd.AddCommand(generateDatasetCommand)
+
+	numCPU := runtime.NumCPU()
+
+	generateDatasetCommand.PersistentFlags().StringVarP(&globalConfig.OutputFile,
+		"output", "o", "", "Path of the hdf5 file to create (mandatory)")
+	generateDatasetCommand.PersistentFlags().StringVar(&globalConfig.Generator,
+		"generator", "gaussian-mixture", "Cluster model (gaussian-mixture or clustered)")
+	generateDatasetCommand.PersistentFlags().IntVar(&globalConfig.GenerateDimensions,
+		"dimensions", 128, "Dimensions of the vectors")
+	generateDatasetCommand.PersistentFlags().IntVar(&globalConfig.IntrinsicDimensions,
+		"intrinsicDimensions", 0, "Dimensions of the space the clusters are drawn in (default the vector dimensions)")
+	generateDatasetCommand.PersistentFlags().IntVar(&globalConfig.Clusters,
+		"clusters", 100, "Number of clusters")
+	generateDatasetCommand.PersistentFlags().Float64Var(&globalConfig.ClusterSpread,
+		"clusterSpread", 0.3, "Standard deviation of the vectors around their cluster center, centers have a deviation of 1")
+	generateDatasetCommand.PersistentFlags().BoolVar(&globalConfig.Normalize,
+		"normalize", false, "Normalize vectors to unit length, e.g. for cosine distance")
+	generateDatasetCommand.PersistentFlags().IntVar(&globalConfig.TrainSize,
+		"trainSize", 100000, "Number of train vectors")
+	generateDatasetCommand.PersistentFlags().IntVar(&globalConfig.TestSize,
+		"testSize", 1000, "Number of test vectors")
+	generateDatasetCommand.PersistentFlags().StringVarP(&globalConfig.GenerateDistanceMetric,
+		"distance", "d", "l2-squared", "Distance metric of the ground truth: cosine, dot, l2-squared, hamming or manhattan")
+	generateDatasetCommand.PersistentFlags().IntVarP(&globalConfig.GroundTruthK,
+		"limit", "l", 100, "Number of neighbors to compute per test vector (k)")
+	generateDatasetCommand.PersistentFlags().Int64Var(&globalConfig.Seed,
+		"seed", 0, "Seed of the generator")
+	generateDatasetCommand.PersistentFlags().IntVarP(&globalConfig.GroundTruthParallel,
+		"parallel", "p", numCPU, "Number of threads that compute the ground truth")
+	generateDatasetCommand.PersistentFlags().IntVarP(&globalConfig.GroundTruthBatchSize,
+		"batchSize", "b", 10000, "Number of train vectors generated and written at once")
+}
+
+// Draws vectors from a mixture of isotropic Gaussian clusters in a latent
+// space, projected to the output dimensions by a random Gaussian matrix
+type mixtureGenerator struct {
+	rng        *rand.Rand
+	centers    [][]float64
+	spreads    []float64
+	cumWeights []float64
+	projection [][]float64
+	dimensions int
+	normalize  bool
+}
+
+func newMixtureGenerator(cfg *Config) *mixtureGenerator {
+	g := &mixtureGenerator{
+		rng:        rand.New(rand.NewSource(cfg.Seed)),
+		dimensions: cfg.Dimensions,
+		normalize:  cfg.Normalize,
+	}
+
+	latent := cfg.IntrinsicDimensions
+	if latent == 0 {
+		latent = cfg.Dimensions
+	}
+
+	total := 0.0
+	for c := 0; c < cfg.Clusters; c++ {
+		center := make([]float64, latent)
+		for i := range center {
+			center[i] = g.rng.NormFloat64()
+		}
+		g.centers = append(g.centers, center)
+
+		weight, spread := 1.0, cfg.ClusterSpread
+		if cfg.Generator == "gaussian-mixture" {
+			weight = g.rng.ExpFloat64()
+			spread *= 0.5 + g.rng.Float64()
+		}
+		total += weight
+		g.cumWeights = append(g.cumWeights, total)
+		g.spreads = append(g.spreads, spread)
+	}
+	for c := range g.cumWeights {
+		g.cumWeights[c] /= total
+	}
+
+	if latent != cfg.Dimensions {
+		// Entries with a variance of 1/latent keep the expected norm
+		scale := 1 / math.Sqrt(float64(latent))
+		g.projection = make([][]float64, cfg.Dimensions)
+		for i := range g.projection {
+			g.projection[i] = make([]float64, latent)
+			for j := range g.projection[i] {
+				g.projection[i][j] = g.rng.NormFloat64() * scale
+			}
+		}
+	}
+
+	return g
+}
+
+func (g *mixtureGenerator) cluster() int {
+	r := g.rng.Float64()
+	for c, w := range g.cumWeights {
+		if r < w {
+			return c
+		}
+	}
+	return len(g.cumWeights) - 1
+}
+
+func (g *mixtureGenerator) vector() []float32 {
+	c := g.cluster()
+	point := make([]float64, len(g.centers[c]))
+	for i := range point {
+		point[i] = g.centers[c][i] + g.rng.NormFloat64()*g.spreads[c]
+	}
+
+	if g.projection != nil {
+		projected := make([]float64, g.dimensions)
+		for i, row := range g.projection {
+			for j, v := range row {
+				projected[i] += v * point[j]
+			}
+		}
+		point = projected
+	}
+
+	norm := 1.0
+	if g.normalize {
+		sum := 0.0
+		for _, v := range point {
+			sum += v * v
+		}
+		if sum > 0 {
+			norm = math.Sqrt(sum)
+		}
+	}
+
+	vector := make([]float32, len(point))
+	for i, v := range point {
+		vector[i] = float32(v / norm)
+	}
+	return vector
+}
+
+// Create the hdf5 file with the train and test datasets, train vectors are
+// generated and written in batches of cfg.BatchSize
+func writeGeneratedDataset(cfg *Config, generator *mixtureGenerator, test [][]float32) error {
+	file, err := hdf5.CreateFile(cfg.OutputFile, hdf5.F_ACC_EXCL)
+	if err != nil {
+		return errors.Wrapf(err, "create %s", cfg.OutputFile)
+	}
+	defer file.Close()
+
+	rows, dimensions := uint(cfg.TrainSize), uint(cfg.Dimensions)
+	filespace, err := hdf5.CreateSimpleDataspace([]uint{rows, dimensions}, nil)
+	if err != nil {
+		return errors.Wrap(err, "create train dataspace")
+	}
+	defer filespace.Close()
+
+	train, err := file.CreateDataset("train", hdf5.T_NATIVE_FLOAT, filespace)
+	if err != nil {
+		return errors.Wrap(err, "create train dataset")
+	}
+	defer train.Close()
+
+	batchSize := uint(cfg.BatchSize)
+	for i := uint(0); i < rows; i += batchSize {
+		batchRows := min(batchSize, rows-i)
+
+		chunk := make([]float32, 0, batchRows*dimensions)
+		for j := uint(0); j < batchRows; j++ {
+			chunk = append(chunk, generator.vector()...)
+		}
+
+		memspace, err := hdf5.CreateSimpleDataspace([]uint{batchRows, dimensions}, nil)
+		if err != nil {
+			return errors.Wrap(err, "create memspace")
+		}
+		if err := filespace.SelectHyperslab([]uint{i, 0}, nil, []uint{batchRows, dimensions}, nil); err != nil {
+			memspace.Close()
+			return errors.Wrap(err, "select hyperslab")
+		}
+		err = train.WriteSubset(&chunk, memspace, filespace)
+		memspace.Close()
+		if err != nil {
+			return errors.Wrapf(err, "write train rows %d to %d", i, i+batchRows)
+		}
+	}
+
+	flat := make([]float32, 0, len(test)*cfg.Dimensions)
+	for _, vector := range test {
+		flat = append(flat, vector...)
+	}
+	return writeHdf5Dataset(file, "test", hdf5.T_NATIVE_FLOAT, []uint{uint(len(test)), dimensions}, &flat)
+}
@@ -0,0 +1,286 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/weaviate/hdf5"
+)
+
+var groundTruthCommand = &cobra.Command{
+	Use:   "ground-truth",
+	Short: "Compute the exact nearest neighbors of the test vectors of a dataset",
+	Long: `Scan all train vectors of a dataset with a multi-threaded brute-force search and write the exact
+top k neighbors and distances of every test vector as the neighbors and distances datasets of an hdf5 file.
+By default they are added to the dataset itself, which then can be used with ann-benchmark.
+
+With --filter only train vectors of the same category as the test vector are neighbors, the categories are
+read from train_categories and test_categories. --categories generates synthetic uniform or normal distributed
+categories instead and writes them to the output as well, for ann-benchmark --filter`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := globalConfig
+		cfg.Mode = "ground-truth"
+		cfg.Limit = cfg.GroundTruthK
+		cfg.BatchSize = cfg.GroundTruthBatchSize
+		cfg.Parallel = cfg.GroundTruthParallel
+
+		if err := cfg.Validate(); err != nil {
+			fatal(err)
+		}
+
+		distance, err := newDistanceFunc(cfg.DistanceMetric)
+		if err != nil {
+			fatal(err)
+		}
+
+		output := cfg.OutputFile
+		if output == "" {
+			output = cfg.BenchmarkFile
+		}
+
+		source := openDatasetSource(&cfg)
+		queries := source.testVectors(&cfg, queryRows(&cfg, source.testCount(&cfg)))
+
+		gt := &groundTruth{}
+		if cfg.Categories > 0 {
+			rng := rand.New(rand.NewSource(cfg.Seed))
+			rows, _ := source.trainExtent(&cfg)
+			gt.trainCategories = generateCategories(int(rows), cfg.Categories, cfg.CategoryDistribution, rng)
+			gt.testCategories = generateCategories(len(queries), cfg.Categories, cfg.CategoryDistribution, rng)
+			gt.generated = true
+		} else if cfg.Filter {
+			gt.trainCategories = source.categories("train_categories")
+			gt.testCategories = source.categories("test_categories")
+		}
+
+		startTime := time.Now()
+		gt.neighbors, gt.distances = computeGroundTruth(source, &cfg, queries, gt.trainCategories, gt.testCategories, distance)
+		// Close the dataset first, the output may be the same hdf5 file
+		source.Close()
+
+		if err := writeHdf5GroundTruth(output, gt); err != nil {
+			log.Fatalf("Error writing ground truth: %v", err)
+		}
+
+		log.WithFields(log.Fields{
+			"queries": len(queries), "k": cfg.Limit, "distance": cfg.DistanceMetric,
+			"filtered": gt.trainCategories != nil, "duration": time.Since(startTime), "output": output,
+		}).Info("Wrote ground truth")
+	},
+}
+
+func initGroundTruth() {
+	rootCmd.AddCommand(groundTruthCommand)
+	addDatasetFlags(groundTruthCommand)
+
+	numCPU := runtime.NumCPU()
+
+	groundTruthCommand.PersistentFlags().StringVarP(&globalConfig.DistanceMetric,
+		"distance", "d", "", "Distance metric: cosine, dot, l2-squared, hamming or manhattan (mandatory)")
+	groundTruthCommand.PersistentFlags().IntVarP(&globalConfig.GroundTruthK,
+		"limit", "l", 100, "Number of neighbors to compute per test vector (k)")
+	groundTruthCommand.PersistentFlags().StringVarP(&globalConfig.OutputFile,
+		"output", "o", "", "hdf5 file to write neighbors and distances to, created if missing (default the dataset itself)")
+	groundTruthCommand.PersistentFlags().IntVarP(&globalConfig.GroundTruthParallel,
+		"parallel", "p", numCPU, "Number of threads that scan the train vectors")
+	groundTruthCommand.PersistentFlags().IntVarP(&globalConfig.GroundTruthBatchSize,
+		"batchSize", "b", 10000, "Number of train vectors read at once")
+	groundTruthCommand.PersistentFlags().BoolVar(&globalConfig.Filter,
+		"filter", false, "Only consider train vectors of the test vector's category, from train_categories and test_categories")
+	groundTruthCommand.PersistentFlags().IntVar(&globalConfig.Categories,
+		"categories", 0, "Generate this many synthetic categories (1 to n) for train and test vectors and filter by them")
+	groundTruthCommand.PersistentFlags().StringVar(&globalConfig.CategoryDistribution,
+		"categoryDistribution", "normal", "Distribution of generated categories (uniform or normal)")
+	groundTruthCommand.PersistentFlags().Int64Var(&globalConfig.Seed,
+		"seed", 0, "Seed of the category generator")
+}
+
+// Neighbors and distances of the test vectors, categories are set for
+// filtered ground truth and written to the output if generated
+type groundTruth struct {
+	neighbors       [][]int
+	distances       [][]float32
+	trainCategories []int
+	testCategories  []int
+	generated       bool
+}
+
+// Categories 1 to n in the same way as scripts/python/generate-filtered-dataset.py,
+// normal distributed categories are centered at n/2 with a deviation of n/4
+func generateCategories(count, n int, distribution string, rng *rand.Rand) []int {
+	midpoint := float64(n / 2)
+	categories := make([]int, count)
+	for i := range categories {
+		if distribution == "uniform" {
+			categories[i] = 1 + rng.Intn(n)
+		} else {
+			categories[i] = int(math.Min(math.Max(rng.NormFloat64()*midpoint/2+midpoint, 1), float64(n)))
+		}
+	}
+	return categories
+}
+
+// Compute the exact top cfg.Limit neighbors of every query, only among the
+// train vectors of the same category if categories are set. The train
+// vectors are streamed once, every chunk is scanned by cfg.Parallel workers
+// that each own a share of the queries. Queries with fewer than cfg.Limit
+// candidates are padded with id -1.
+func computeGroundTruth(source datasetSource, cfg *Config, queries [][]float32, trainCategories, testCategories []int,
+	distance distanceFunc,
+) ([][]int, [][]float32) {
+	rows, dimensions := source.trainExtent(cfg)
+	if len(queries) > 0 && len(queries[0]) != int(dimensions) {
+		log.Fatalf("Test vectors have %d dimensions, train vectors %d", len(queries[0]), dimensions)
+	}
+
+	results := make([]*topK, len(queries))
+	for i := range results {
+		results[i] = newTopK(cfg.Limit)
+	}
+
+	chunks := make(chan Batch, 2)
+	go func() {
+		source.streamTrain(chunks, cfg, 0, 0, nil)
+		close(chunks)
+	}()
+
+	var scanned atomic.Int64
+	startTime := time.Now()
+	progress := startProgress(func() (string, log.Fields) {
+		done := scanned.Load()
+		fields := log.Fields{"rows": fmt.Sprintf("%d/%d", done, rows)}
+		if avg := float64(done) / time.Since(startTime).Seconds(); avg > 0 && done < int64(rows) {
+			fields["rows/s"] = fmt.Sprintf("%.0f", avg)
+			fields["eta"] = (time.Duration(float64(int64(rows)-done)/avg) * time.Second).Round(time.Second)
+		}
+		return "Computing ground truth", fields
+	})
+
+	workers := min(cfg.Parallel, max(len(queries), 1))
+	for chunk := range chunks {
+		wg := sync.WaitGroup{}
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(from, to int) {
+				defer wg.Done()
+				for q := from; q < to; q++ {
+					for j, vector := range chunk.Vectors {
+						if trainCategories != nil && trainCategories[chunk.Offset+j] != testCategories[q] {
+							continue
+						}
+						results[q].insert(chunk.Offset+j, distance(queries[q], vector))
+					}
+				}
+			}(w*len(queries)/workers, (w+1)*len(queries)/workers)
+		}
+		wg.Wait()
+		scanned.Add(int64(len(chunk.Vectors)))
+	}
+	progress.Stop()
+
+	neighbors := make([][]int, len(queries))
+	distances := make([][]float32, len(queries))
+	incomplete := 0
+	for i, result := range results {
+		neighbors[i], distances[i] = result.sorted()
+		if len(neighbors[i]) < cfg.Limit {
+			incomplete++
+		}
+		for len(neighbors[i]) < cfg.Limit {
+			neighbors[i] = append(neighbors[i], -1)
+			distances[i] = append(distances[i], math.MaxFloat32)
+		}
+	}
+	if incomplete > 0 {
+		log.Warnf("%d of %d test vectors have fewer than %d neighbors, padded with -1", incomplete, len(queries), cfg.Limit)
+	}
+	return neighbors, distances
+}
+
+// Add neighbors and distances datasets to an hdf5 file in the layout of
+// ann-benchmarks.com, the file is created if it does not exist
+func writeHdf5GroundTruth(path string, gt *groundTruth) error {
+	var file *hdf5.File
+	var err error
+	if _, statErr := os.Stat(path); statErr == nil {
+		file, err = hdf5.OpenFile(path, hdf5.F_ACC_RDWR)
+	} else {
+		file, err = hdf5.CreateFile(path, hdf5.F_ACC_EXCL)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "open %s", path)
+	}
+	defer file.Close()
+
+	names := []string{"neighbors", "distances"}
+	if gt.generated {
+		names = append(names, "train_categories", "test_categories")
+	}
+	for _, name := range names {
+		if file.LinkExists(name) {
+			return errors.Errorf("%s already has a %s dataset, write to a new file with --output", path, name)
+		}
+	}
+
+	rows := len(gt.neighbors)
+	k := 0
+	if rows > 0 {
+		k = len(gt.neighbors[0])
+	}
+
+	ids := make([]int32, 0, rows*k)
+	dists := make([]float32, 0, rows*k)
+	for i := range gt.neighbors {
+		for _, id := range gt.neighbors[i] {
+			ids = append(ids, int32(id))
+		}
+		dists = append(dists, gt.distances[i]...)
+	}
+
+	if err := writeHdf5Dataset(file, "neighbors", hdf5.T_NATIVE_INT32, []uint{uint(rows), uint(k)}, &ids); err != nil {
+		return err
+	}
+	if err := writeHdf5Dataset(file, "distances", hdf5.T_NATIVE_FLOAT, []uint{uint(rows), uint(k)}, &dists); err != nil {
+		return err
+	}
+
+	if gt.generated {
+		for name, categories := range map[string][]int{
+			"train_categories": gt.trainCategories, "test_categories": gt.testCategories,
+		} {
+			values := make([]int64, len(categories))
+			for i, c := range categories {
+				values[i] = int64(c)
+			}
+			if err := writeHdf5Dataset(file, name, hdf5.T_NATIVE_INT64, []uint{uint(len(values))}, &values); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeHdf5Dataset(file *hdf5.File, name string, dtype *hdf5.Datatype, dims []uint, data interface{}) error {
+	dataspace, err := hdf5.CreateSimpleDataspace(dims, nil)
+	if err != nil {
+		return errors.Wrapf(err, "create %s dataspace", name)
+	}
+	defer dataspace.Close()
+
+	dataset, err := file.CreateDataset(name, dtype, dataspace)
+	if err != nil {
+		return errors.Wrapf(err, "create %s dataset", name)
+	}
+	defer dataset.Close()
+
+	return errors.Wrapf(dataset.Write(data), "write %s dataset", name)
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// Import throughput is reported in 10 second intervals, imports run for
+// minutes to hours and the series is part of every result record
+const importThroughputInterval = 10 * time.Second
+
+// Distinct per-object error messages that are counted separately, any
+// further messages are counted as "other"
+const importMaxErrorMessages = 20
+
+var importBatchPercentiles = []float64{50, 90, 99, 99.9}
+
+// Collects batch latencies, throughput and errors of an import, shared by
+// all import workers
+type importStats struct {
+	sync.Mutex
+	start         time.Time
+	end           time.Time
+	batches       *hdrhistogram.Histogram
+	objects       int
+	failedBatches int
+	objectErrors  int
+	errors        map[string]int
+	intervals     []int
+
+	// Incremented for every attempt of a gRPC call, including retries
+	attempts atomic.Int64
+}
+
+// Import statistics as written to the results, latencies in seconds
+type ImportResults struct {
+	Objects          int                `json:"objects"`
+	ObjectsPerSecond float64            `json:"objectsPerSecond"`
+	Batches          int64              `json:"batches"`
+	FailedBatches    int                `json:"failedBatches"`
+	ObjectErrors     int                `json:"objectErrors"`
+	Errors           map[string]int     `json:"errors,omitempty"`
+	Retries          int64              `json:"retries"`
+	BatchLatencies   map[string]float64 `json:"batchLatencies"`
+	Throughput       []ImportInterval   `json:"throughput"`
+}
+
+type ImportInterval struct {
+	Timestamp        time.Time `json:"timestamp"`
+	Objects          int       `json:"objects"`
+	ObjectsPerSecond float64   `json:"objectsPerSecond"`
+}
+
+func newImportStats() *importStats {
+	return &importStats{
+		start:   time.Now(),
+		batches: newLatencyHistogram(),
+		errors:  map[string]int{},
+	}
+}
+
+// Record a batch that was answered, objectErrors are the error messages of
+// the objects the server reported as failed
+func (s *importStats) recordBatch(took time.Duration, objects int, objectErrors []string) {
+	now := time.Now()
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.batches.RecordValue(clampLatency(took))
+	s.objects += objects
+	if now.After(s.end) {
+		s.end = now
+	}
+
+	idx := int(now.Sub(s.start) / importThroughputInterval)
+	for len(s.intervals) <= idx {
+		s.intervals = append(s.intervals, 0)
+	}
+	s.intervals[idx] += objects
+
+	if len(objectErrors) == 0 {
+		return
+	}
+	s.failedBatches++
+	s.objectErrors += len(objectErrors)
+	for _, msg := range objectErrors {
+		if _, ok := s.errors[msg]; !ok && len(s.errors) >= importMaxErrorMessages {
+			msg = "other"
+		}
+		s.errors[msg]++
+	}
+}
+
+// Chained after the retry interceptor so every attempt is counted, the
+// number of retries is the number of attempts minus the number of batches
+func (s *importStats) countAttempts() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		s.attempts.Add(1)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func (s *importStats) results() *ImportResults {
+	s.Lock()
+	defer s.Unlock()
+
+	out := &ImportResults{
+		Objects:        s.objects,
+		Batches:        s.batches.TotalCount(),
+		FailedBatches:  s.failedBatches,
+		ObjectErrors:   s.objectErrors,
+		Errors:         s.errors,
+		Retries:        max(s.attempts.Load()-s.batches.TotalCount(), 0),
+		BatchLatencies: map[string]float64{},
+	}
+
+	if took := s.end.Sub(s.start); took > 0 {
+		out.ObjectsPerSecond = float64(s.objects) / took.Seconds()
+	}
+
+	if out.Batches > 0 {
+		out.BatchLatencies["mean"] = (time.Duration(s.batches.Mean()) * time.Microsecond).Seconds()
+		out.BatchLatencies["max"] = (time.Duration(s.batches.Max()) * time.Microsecond).Seconds()
+		for _, percentile := range importBatchPercentiles {
+			out.BatchLatencies[percentileLabel(percentile)] = histogramPercentile(s.batches, percentile).Seconds()
+		}
+	}
+
+	for i, objects := range s.intervals {
+		intervalStart := s.start.Add(time.Duration(i) * importThroughputInterval)
+		duration := importThroughputInterval
+		if remaining := s.end.Sub(intervalStart); remaining < duration && remaining > 0 {
+			duration = remaining
+		}
+		out.Throughput = append(out.Throughput, ImportInterval{
+			Timestamp:        intervalStart,
+			Objects:          objects,
+			ObjectsPerSecond: float64(objects) / duration.Seconds(),
+		})
+	}
+
+	return out
+}
+
+func logImportResults(r *ImportResults) {
+	log.WithFields(log.Fields{
+		"objects": r.Objects, "objectsPerSecond": r.ObjectsPerSecond, "batches": r.Batches,
+		"batchP50": r.BatchLatencies["p50"], "batchP99": r.BatchLatencies["p99"],
+		"objectErrors": r.ObjectErrors, "retries": r.Retries,
+	}).Info("Import statistics")
+}
+
+// Combine the imports of several tenants into one result
+func mergeImportStats(stats []*importStats) *importStats {
+	merged := newImportStats()
+	if len(stats) > 0 {
+		merged.start = stats[0].start
+	}
+
+	for _, s := range stats {
+		s.Lock()
+		merged.batches.Merge(s.batches)
+		merged.objects += s.objects
+		merged.failedBatches += s.failedBatches
+		merged.objectErrors += s.objectErrors
+		for msg, count := range s.errors {
+			merged.errors[msg] += count
+		}
+		merged.attempts.Add(s.attempts.Load())
+		if s.end.After(merged.end) {
+			merged.end = s.end
+		}
+
+		offset := int(s.start.Sub(merged.start) / importThroughputInterval)
+		for i, objects := range s.intervals {
+			for len(merged.intervals) <= offset+i {
+				merged.intervals = append(merged.intervals, 0)
+			}
+			merged.intervals[offset+i] += objects
+		}
+		s.Unlock()
+	}
+
+	return merged
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"math"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Latencies are recorded in microseconds from 1µs up to 10 minutes with 3
+// significant digits, which keeps a histogram at a fixed ~160KB no matter
+// how many queries are recorded
+const (
+	histogramLowestLatency  = 1
+	histogramHighestLatency = int64(10 * time.Minute / time.Microsecond)
+	histogramSignificant    = 3
+)
+
+func newLatencyHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(histogramLowestLatency, histogramHighestLatency, histogramSignificant)
+}
+
+// Records the latencies and recall of a single query worker. Every worker
+// owns its recorder so recording needs no locking, the recorders are merged
+// once all workers are done. Min, max and mean are tracked exactly next to
+// the histogram which is used for percentiles. If a timeline is set the
+// current interval is tracked as well.
+type latencyRecorder struct {
+	histogram   *hdrhistogram.Histogram
+	count       int
+	sum         time.Duration
+	min         time.Duration
+	max         time.Duration
+	recallSum   float64
+	recallCount int
+	failed      int
+	errors      map[string]int
+	// Queries that returned fewer than cfg.Limit results
+	shortResults int
+
+	distanceErrorSum   float64
+	distanceErrorCount int
+	qualitySum         retrievalQuality
+	qrelsCount         int
+
+	budget      *errorBudget
+	timeline    *timeline
+	interval    *timelineBucket
+	intervalIdx int
+	// The bucket of the previous interval, reused for the next one
+	spare *timelineBucket
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{
+		histogram: newLatencyHistogram(),
+		min:       math.MaxInt64,
+		errors:    map[string]int{},
+	}
+}
+
+func (r *latencyRecorder) record(took time.Duration) {
+	r.histogram.RecordValue(clampLatency(took))
+	r.count++
+	r.sum += took
+	if took < r.min {
+		r.min = took
+	}
+	if took > r.max {
+		r.max = took
+	}
+	if r.budget != nil {
+		r.budget.success()
+	}
+
+	if b := r.intervalBucket(); b != nil {
+		b.histogram.RecordValue(clampLatency(took))
+		b.count++
+	}
+}
+
+func (r *latencyRecorder) recordRecall(recall float64) {
+	r.recallSum += recall
+	r.recallCount++
+
+	if b := r.intervalBucket(); b != nil {
+		b.recallSum += recall
+		b.recallCount++
+	}
+}
+
+// Record the recall, distance error and retrieval quality of a query, each
+// only if the query has neighbors, distances or relevance judgments
+func (r *latencyRecorder) recordScore(score queryScore) {
+	if score.hasRecall {
+		r.recordRecall(score.recall)
+	}
+	if score.shortResults {
+		r.shortResults++
+	}
+	if score.hasDistances {
+		r.distanceErrorSum += score.distanceError
+		r.distanceErrorCount++
+	}
+	if score.hasQrels {
+		r.qualitySum = r.qualitySum.add(score.quality)
+		r.qrelsCount++
+	}
+}
+
+// Count a failed query by its error class
+func (r *latencyRecorder) recordError(err error) {
+	r.failed++
+	r.errors[errorClass(err)]++
+
+	if b := r.intervalBucket(); b != nil {
+		b.errors++
+	}
+	if r.budget != nil {
+		r.budget.failure(err)
+	}
+}
+
+// Returns the bucket of the current interval or nil without a timeline, the
+// previous interval is merged into the timeline once the worker moved past it
+func (r *latencyRecorder) intervalBucket() *timelineBucket {
+	if r.timeline == nil {
+		return nil
+	}
+
+	idx := r.timeline.index(time.Now())
+	if r.interval != nil && idx != r.intervalIdx {
+		r.flushInterval()
+	}
+	if r.interval == nil {
+		r.interval, r.spare = r.spare, nil
+		if r.interval == nil {
+			r.interval = newTimelineBucket()
+		}
+		r.intervalIdx = idx
+	}
+	return r.interval
+}
+
+func (r *latencyRecorder) flushInterval() {
+	if r.interval == nil {
+		return
+	}
+	r.timeline.merge(r.intervalIdx, r.interval)
+	r.interval.reset()
+	r.spare, r.interval = r.interval, nil
+}
+
+func (r *latencyRecorder) merge(other *latencyRecorder) {
+	other.flushInterval()
+	r.histogram.Merge(other.histogram)
+	r.count += other.count
+	r.sum += other.sum
+	r.recallSum += other.recallSum
+	r.recallCount += other.recallCount
+	r.distanceErrorSum += other.distanceErrorSum
+	r.distanceErrorCount += other.distanceErrorCount
+	r.qualitySum = r.qualitySum.add(other.qualitySum)
+	r.qrelsCount += other.qrelsCount
+	r.failed += other.failed
+	r.shortResults += other.shortResults
+	for class, count := range other.errors {
+		r.errors[class] += count
+	}
+	if other.min < r.min {
+		r.min = other.min
+	}
+	if other.max > r.max {
+		r.max = other.max
+	}
+}
+
+func mergeLatencyRecorders(recorders []*latencyRecorder) *latencyRecorder {
+	merged := newLatencyRecorder()
+	for _, r := range recorders {
+		merged.merge(r)
+	}
+	return merged
+}
+
+// Convert a duration to the histogram unit, values outside of the trackable
+// range are clamped instead of dropped
+func clampLatency(took time.Duration) int64 {
+	v := int64(took / time.Microsecond)
+	if v < histogramLowestLatency {
+		return histogramLowestLatency
+	}
+	if v > histogramHighestLatency {
+		return histogramHighestLatency
+	}
+	return v
+}
+
+func histogramPercentile(h *hdrhistogram.Histogram, percentile float64) time.Duration {
+	return time.Duration(h.ValueAtPercentile(percentile)) * time.Microsecond
+}
+
+// A non-empty bucket of the latency histogram, bounds in nanoseconds
+type histogramBucket struct {
+	From  int64 `json:"from"`
+	To    int64 `json:"to"`
+	Count int64 `json:"count"`
+}
+
+// Returns the non-empty buckets of the histogram
+func histogramBuckets(h *hdrhistogram.Histogram) []histogramBucket {
+	if h == nil {
+		return nil
+	}
+	var buckets []histogramBucket
+	for _, bar := range h.Distribution() {
+		if bar.Count == 0 {
+			continue
+		}
+		buckets = append(buckets, histogramBucket{
+			From:  int64(time.Duration(bar.From) * time.Microsecond),
+			To:    int64(time.Duration(bar.To) * time.Microsecond),
+			Count: bar.Count,
+		})
+	}
+	return buckets
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+// Client-side metrics of the benchmarker, served on --metricsListen. They
+// are always recorded but only exposed if the flag is set.
+var benchmarkerMetrics = newBenchmarkerMetrics()
+
+type metricsSet struct {
+	registry *prometheus.Registry
+
+	queryDuration       *prometheus.HistogramVec
+	queryErrors         *prometheus.CounterVec
+	queriesInFlight     prometheus.Gauge
+	importBatchDuration prometheus.Histogram
+	importBatchErrors   prometheus.Counter
+	importObjectErrors  prometheus.Counter
+	importedObjects     prometheus.Counter
+	phase               *prometheus.GaugeVec
+
+	// Query metrics with the ef of the current phase resolved, so workers
+	// neither lock nor look up labels per query
+	current atomic.Pointer[phaseMetrics]
+}
+
+type phaseMetrics struct {
+	queryDuration prometheus.Observer
+	queryErrors   *prometheus.CounterVec
+}
+
+func newBenchmarkerMetrics() *metricsSet {
+	m := &metricsSet{
+		registry: prometheus.NewRegistry(),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "benchmarker_query_duration_seconds",
+			Help:    "Client-observed latency of successful queries",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 16),
+		}, []string{"ef"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "benchmarker_query_errors_total",
+			Help: "Number of failed queries by gRPC status code or http status",
+		}, []string{"ef", "class"}),
+		queriesInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "benchmarker_queries_in_flight",
+			Help: "Number of queries sent and not yet answered",
+		}),
+		importBatchDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "benchmarker_import_batch_duration_seconds",
+			Help:    "Client-observed latency of import batch requests",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 16),
+		}),
+		importBatchErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "benchmarker_import_batch_errors_total",
+			Help: "Number of import batches with at least one failed object",
+		}),
+		importObjectErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "benchmarker_import_object_errors_total",
+			Help: "Number of objects reported as failed in import batch responses",
+		}),
+		importedObjects: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "benchmarker_imported_objects_total",
+			Help: "Number of objects sent in import batches",
+		}),
+		phase: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "benchmarker_phase",
+			Help: "Set to 1 for the current phase (import or query) and ef of the run",
+		}, []string{"phase", "ef"}),
+	}
+
+	m.registry.MustRegister(m.queryDuration, m.queryErrors, m.queriesInFlight,
+		m.importBatchDuration, m.importBatchErrors, m.importObjectErrors,
+		m.importedObjects, m.phase)
+	m.current.Store(m.phaseMetrics(0))
+
+	return m
+}
+
+func (m *metricsSet) phaseMetrics(ef int) *phaseMetrics {
+	label := strconv.Itoa(ef)
+	return &phaseMetrics{
+		queryDuration: m.queryDuration.WithLabelValues(label),
+		queryErrors:   m.queryErrors.MustCurryWith(prometheus.Labels{"ef": label}),
+	}
+}
+
+func (m *metricsSet) setPhase(name string, ef int) {
+	m.phase.Reset()
+	m.phase.WithLabelValues(name, strconv.Itoa(ef)).Set(1)
+	m.current.Store(m.phaseMetrics(ef))
+}
+
+func (m *metricsSet) observeQuery(took time.Duration) {
+	m.current.Load().queryDuration.Observe(took.Seconds())
+}
+
+func (m *metricsSet) queryFailed(class string) {
+	m.current.Load().queryErrors.WithLabelValues(class).Inc()
+}
+
+// Serve the benchmarker metrics in the background for the rest of the run
+func startMetricsServer(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(benchmarkerMetrics.registry, promhttp.HandlerOpts{}))
+
+	go func() {
+		log.Infof("Serving benchmarker metrics on %s/metrics", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Fatalf("Error serving metrics: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,448 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	npyMagic       = []byte("\x93NUMPY")
+	npyDescrRe     = regexp.MustCompile(`'descr':\s*'([<>|=])([fiu])(\d+)'`)
+	npyFortranRe   = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	npyShapeRe     = regexp.MustCompile(`'shape':\s*\(([^)]*)\)`)
+	npyDatasetKeys = []string{"train", "test", "neighbors", "train_categories", "test_categories"}
+)
+
+// A NumPy array in .npy format (https://numpy.org/doc/stable/reference/generated/numpy.lib.format.html).
+// Arrays are read sequentially so members of compressed .npz archives can be
+// streamed as well.
+type npyArray struct {
+	path      string
+	open      func() (io.ReadCloser, error)
+	kind      byte
+	itemSize  int
+	bigEndian bool
+	shape     []int
+	// Size of the magic string, version and header before the data
+	dataOffset int64
+}
+
+func openNpyArray(path string, open func() (io.ReadCloser, error)) (*npyArray, error) {
+	r, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	a := &npyArray{path: path, open: open}
+
+	prefix := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, errors.Wrapf(err, "%s: read header", path)
+	}
+	if string(prefix[:len(npyMagic)]) != string(npyMagic) {
+		return nil, errors.Errorf("%s: not a .npy file", path)
+	}
+
+	var headerLen int
+	switch major := prefix[len(npyMagic)]; major {
+	case 1:
+		buf := make([]byte, 2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, errors.Wrapf(err, "%s: read header", path)
+		}
+		headerLen = int(binary.LittleEndian.Uint16(buf))
+		a.dataOffset = int64(len(prefix) + 2 + headerLen)
+	case 2, 3:
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, errors.Wrapf(err, "%s: read header", path)
+		}
+		headerLen = int(binary.LittleEndian.Uint32(buf))
+		a.dataOffset = int64(len(prefix) + 4 + headerLen)
+	default:
+		return nil, errors.Errorf("%s: unsupported .npy version %d", path, major)
+	}
+
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.Wrapf(err, "%s: read header", path)
+	}
+
+	descr := npyDescrRe.FindStringSubmatch(string(header))
+	if descr == nil {
+		return nil, errors.Errorf("%s: unsupported dtype in header %s", path, strings.TrimSpace(string(header)))
+	}
+	a.bigEndian = descr[1] == ">"
+	a.kind = descr[2][0]
+	a.itemSize, _ = strconv.Atoi(descr[3])
+
+	if fortran := npyFortranRe.FindStringSubmatch(string(header)); fortran == nil || fortran[1] == "True" {
+		return nil, errors.Errorf("%s: only C order arrays are supported", path)
+	}
+
+	shape := npyShapeRe.FindStringSubmatch(string(header))
+	if shape == nil {
+		return nil, errors.Errorf("%s: no shape in header", path)
+	}
+	for _, dim := range strings.Split(shape[1], ",") {
+		if dim = strings.TrimSpace(dim); dim == "" {
+			continue
+		}
+		n, err := strconv.Atoi(dim)
+		if err != nil {
+			return nil, errors.Errorf("%s: invalid shape %q", path, shape[1])
+		}
+		a.shape = append(a.shape, n)
+	}
+
+	return a, nil
+}
+
+func (a *npyArray) rows() int {
+	if len(a.shape) == 0 {
+		return 0
+	}
+	return a.shape[0]
+}
+
+// Number of values per row, 1 for one dimensional arrays
+func (a *npyArray) columns() int {
+	columns := 1
+	for _, dim := range a.shape[1:] {
+		columns *= dim
+	}
+	return columns
+}
+
+// Read count rows starting at row start and pass them to fn in chunks of up
+// to batchSize rows, each value is decoded by value
+func (a *npyArray) stream(start, count, batchSize int, value func(b []byte) float64, fn func(offset int, rows [][]float64)) error {
+	r, err := a.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	rowSize := a.columns() * a.itemSize
+	br := bufio.NewReaderSize(r, 1<<20)
+	if _, err := br.Discard(int(a.dataOffset) + start*rowSize); err != nil {
+		return errors.Wrapf(err, "%s: seek to row %d", a.path, start)
+	}
+
+	buf := make([]byte, batchSize*rowSize)
+	for offset := start; offset < start+count; offset += batchSize {
+		n := min(batchSize, start+count-offset)
+		if _, err := io.ReadFull(br, buf[:n*rowSize]); err != nil {
+			return errors.Wrapf(err, "%s: read rows %d to %d", a.path, offset, offset+n)
+		}
+
+		rows := make([][]float64, n)
+		for i := range rows {
+			rows[i] = make([]float64, a.columns())
+			for j := range rows[i] {
+				pos := i*rowSize + j*a.itemSize
+				rows[i][j] = value(buf[pos : pos+a.itemSize])
+			}
+		}
+		fn(offset, rows)
+	}
+	return nil
+}
+
+func (a *npyArray) byteOrder() binary.ByteOrder {
+	if a.bigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// Decoder of float16, float32 and float64 values
+func (a *npyArray) floatValue() (func(b []byte) float64, error) {
+	order := a.byteOrder()
+	if a.kind != 'f' {
+		return nil, errors.Errorf("%s: expected a float array, got dtype %c%d", a.path, a.kind, a.itemSize)
+	}
+	switch a.itemSize {
+	case 2:
+		return func(b []byte) float64 { return float64(float16ToFloat32(order.Uint16(b))) }, nil
+	case 4:
+		return func(b []byte) float64 { return float64(math.Float32frombits(order.Uint32(b))) }, nil
+	case 8:
+		return func(b []byte) float64 { return math.Float64frombits(order.Uint64(b)) }, nil
+	default:
+		return nil, errors.Errorf("%s: unsupported float size %d", a.path, a.itemSize)
+	}
+}
+
+// Decoder of int32 and int64 values
+func (a *npyArray) intValue() (func(b []byte) float64, error) {
+	order := a.byteOrder()
+	if a.kind != 'i' {
+		return nil, errors.Errorf("%s: expected an int array, got dtype %c%d", a.path, a.kind, a.itemSize)
+	}
+	switch a.itemSize {
+	case 4:
+		return func(b []byte) float64 { return float64(int32(order.Uint32(b))) }, nil
+	case 8:
+		return func(b []byte) float64 { return float64(int64(order.Uint64(b))) }, nil
+	default:
+		return nil, errors.Errorf("%s: unsupported int size %d", a.path, a.itemSize)
+	}
+}
+
+// Read the rows at the ascending indices rows, or all rows if nil, in a
+// single pass over the array
+func (a *npyArray) selectRows(rows []int, value func(b []byte) float64) ([][]float64, error) {
+	if rows == nil {
+		out := make([][]float64, 0, a.rows())
+		err := a.stream(0, a.rows(), max(a.rows(), 1), value, func(offset int, chunk [][]float64) {
+			out = append(out, chunk...)
+		})
+		return out, err
+	}
+
+	if err := checkQueryRows(rows, a.rows()); err != nil {
+		return nil, err
+	}
+
+	out := make([][]float64, 0, len(rows))
+	next := 0
+	err := a.stream(0, rows[len(rows)-1]+1, 1024, value, func(offset int, chunk [][]float64) {
+		for next < len(rows) && rows[next] < offset+len(chunk) {
+			out = append(out, chunk[rows[next]-offset])
+			next++
+		}
+	})
+	return out, err
+}
+
+// Read the selected rows as vectors
+func (a *npyArray) float32Rows(rows []int) ([][]float32, error) {
+	value, err := a.floatValue()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := a.selectRows(rows, value)
+	return toFloat32Rows(out), err
+}
+
+// Read the selected rows as ints, e.g. neighbors or categories
+func (a *npyArray) intRows(rows []int) ([][]int, error) {
+	value, err := a.intValue()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := a.selectRows(rows, value)
+	out := make([][]int, len(selected))
+	for i, row := range selected {
+		out[i] = make([]int, len(row))
+		for j, v := range row {
+			out[i][j] = int(v)
+		}
+	}
+	return out, err
+}
+
+func toFloat32Rows(rows [][]float64) [][]float32 {
+	out := make([][]float32, len(rows))
+	for i, row := range rows {
+		out[i] = make([]float32, len(row))
+		for j, v := range row {
+			out[i][j] = float32(v)
+		}
+	}
+	return out
+}
+
+func flatten[D any](rows [][]D) []D {
+	var out []D
+	for _, row := range rows {
+		out = append(out, row...)
+	}
+	return out
+}
+
+// Convert an IEEE 754 half precision float
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exponent := uint32(h>>10) & 0x1f
+	mantissa := uint32(h) & 0x3ff
+
+	switch {
+	case exponent == 0x1f:
+		// Inf and NaN
+		return math.Float32frombits(sign | 0xff<<23 | mantissa<<13)
+	case exponent != 0:
+		return math.Float32frombits(sign | (exponent+127-15)<<23 | mantissa<<13)
+	case mantissa == 0:
+		return math.Float32frombits(sign)
+	default:
+		// Subnormal half floats are normal in float32
+		exponent = 127 - 15 + 1
+		for mantissa&0x400 == 0 {
+			mantissa <<= 1
+			exponent--
+		}
+		return math.Float32frombits(sign | exponent<<23 | (mantissa&0x3ff)<<13)
+	}
+}
+
+// A dataset of NumPy arrays with the same keys as an ann-benchmarks hdf5
+// file, either an .npz archive or a directory of <key>.npy files
+type npySource struct {
+	arrays map[string]*npyArray
+	zip    *zip.ReadCloser
+}
+
+func openNpySource(cfg *Config) *npySource {
+	if cfg.MultiVectorDimensions > 0 {
+		log.Fatalf("multi-vector datasets are only supported in hdf5 format")
+	}
+
+	s := &npySource{arrays: map[string]*npyArray{}}
+
+	if strings.HasSuffix(strings.ToLower(cfg.BenchmarkFile), ".npz") {
+		archive, err := zip.OpenReader(cfg.BenchmarkFile)
+		if err != nil {
+			log.Fatalf("Error opening dataset: %v", err)
+		}
+		s.zip = archive
+
+		for _, f := range archive.File {
+			key := strings.TrimSuffix(f.Name, ".npy")
+			if !slices.Contains(npyDatasetKeys, key) {
+				continue
+			}
+			array, err := openNpyArray(cfg.BenchmarkFile+":"+f.Name, f.Open)
+			if err != nil {
+				log.Fatalf("Error opening dataset: %v", err)
+			}
+			s.arrays[key] = array
+		}
+	} else {
+		for _, key := range npyDatasetKeys {
+			path := filepath.Join(cfg.BenchmarkFile, key+".npy")
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			array, err := openNpyArray(path, func() (io.ReadCloser, error) { return os.Open(path) })
+			if err != nil {
+				log.Fatalf("Error opening dataset: %v", err)
+			}
+			s.arrays[key] = array
+		}
+	}
+
+	train := s.array("train")
+	if len(train.shape) != 2 {
+		log.Fatalf("%s: expected 2 dimensions, got shape %v", train.path, train.shape)
+	}
+
+	return s
+}
+
+func (s *npySource) array(key string) *npyArray {
+	array, ok := s.arrays[key]
+	if !ok {
+		log.Fatalf("Dataset has no %s array", key)
+	}
+	return array
+}
+
+func (s *npySource) trainExtent(cfg *Config) (uint, uint) {
+	train := s.array("train")
+	return uint(train.rows()), uint(train.columns())
+}
+
+func (s *npySource) streamTrain(chunks chan<- Batch, cfg *Config, startOffset uint, maxRecords uint, filters []int) {
+	train := s.array("train")
+	rows := uint(train.rows())
+
+	i := uint(0)
+	if maxRecords != 0 && maxRecords < rows {
+		rows = maxRecords
+	}
+
+	if startOffset != 0 && i < rows {
+		i = startOffset
+	}
+	if i >= rows {
+		return
+	}
+
+	log.WithFields(log.Fields{"rows": rows, "dimensions": train.columns()}).Printf(
+		"Reading NumPy dataset")
+
+	value, err := train.floatValue()
+	if err != nil {
+		log.Fatalf("Error reading dataset: %v", err)
+	}
+
+	err = train.stream(int(i), int(rows-i), cfg.BatchSize, value, func(offset int, batch [][]float64) {
+		chunk := Batch{
+			Vectors: toFloat32Rows(batch),
+			Offset:  offset,
+			Filters: []int{},
+		}
+		if len(filters) > 0 {
+			chunk.Filters = filters[offset : offset+len(batch)]
+		}
+		chunks <- chunk
+	})
+	if err != nil {
+		log.Fatalf("Error reading dataset: %v", err)
+	}
+}
+
+func (s *npySource) testCount(cfg *Config) int {
+	return s.array("test").rows()
+}
+
+func (s *npySource) testVectors(cfg *Config, rows []int) [][]float32 {
+	vectors, err := s.array("test").float32Rows(rows)
+	if err != nil {
+		log.Fatalf("Error reading test vectors: %v", err)
+	}
+	return vectors
+}
+
+func (s *npySource) neighbors(cfg *Config, rows []int) [][]int {
+	neighbors, err := s.array("neighbors").intRows(rows)
+	if err != nil {
+		log.Fatalf("Error reading neighbors: %v", err)
+	}
+	return neighbors
+}
+
+func (s *npySource) distances(cfg *Config, rows []int) [][]float32 {
+	return nil
+}
+
+func (s *npySource) categories(name string) []int {
+	rows, err := s.array(name).intRows(nil)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", name, err)
+	}
+	return flatten(rows)
+}
+
+func (s *npySource) Close() {
+	if s.zip != nil {
+		s.zip.Close()
+	}
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// A query together with the time it was scheduled to be sent
+type scheduledQuery struct {
+	Query    QueryWithNeighbors
+	Intended time.Time
+}
+
+// Returns the gap between two consecutive arrivals for the configured
+// target rate, either constant or exponentially distributed (poisson)
+func nextArrival(cfg *Config, r *rand.Rand) time.Duration {
+	interval := float64(time.Second) / cfg.TargetQPS
+	if cfg.Arrival == "poisson" {
+		return time.Duration(r.ExpFloat64() * interval)
+	}
+	return time.Duration(interval)
+}
+
+// Send queries open-loop at cfg.TargetQPS. The send schedule is fixed up
+// front and does not wait for responses, so latencies passed to send are
+// measured from the intended send time and include any time spent queued
+// behind a saturated server (no coordinated omission). cfg.Parallel bounds
+// the number of in-flight requests.
+func runOpenLoop(cfg *Config, queries []QueryWithNeighbors, send func(worker int, query QueryWithNeighbors, intended time.Time)) {
+	jobs := make(chan scheduledQuery, cfg.Parallel)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < cfg.Parallel; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for job := range jobs {
+				send(worker, job.Query, job.Intended)
+			}
+		}(i)
+	}
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	intended := time.Now()
+	for _, query := range queries {
+		if wait := time.Until(intended); wait > 0 {
+			time.Sleep(wait)
+		}
+		jobs <- scheduledQuery{Query: query, Intended: intended}
+		intended = intended.Add(nextArrival(cfg, r))
+	}
+	close(jobs)
+
+	wg.Wait()
+}
@@ -0,0 +1,395 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Rows read from a row group at once when looking up test vectors or
+// neighbors, training rows are read in batches of cfg.BatchSize
+const parquetReadRows = 1024
+
+// A Parquet file with one row per object. The vector is a list<float> or
+// list<double> column, other top level columns can be imported as properties.
+type parquetFile struct {
+	file *os.File
+	pq   *parquet.File
+	path string
+}
+
+// A leaf column of the file, repeated columns are lists
+type parquetColumn struct {
+	name     string
+	index    int
+	repeated bool
+}
+
+func openParquetFile(path string) (*parquetFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	pq, err := parquet.OpenFile(f, stat.Size())
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "%s: open parquet", path)
+	}
+
+	return &parquetFile{file: f, pq: pq, path: path}, nil
+}
+
+// Look up a top level column by name, nested columns other than a list of
+// scalars are not supported
+func (p *parquetFile) column(name string) (parquetColumn, error) {
+	var leaves [][]string
+	for _, path := range p.pq.Schema().Columns() {
+		if path[0] == name {
+			leaves = append(leaves, path)
+		}
+	}
+	if len(leaves) == 0 {
+		return parquetColumn{}, errors.Errorf("%s: no column %q", p.path, name)
+	}
+	if len(leaves) > 1 {
+		return parquetColumn{}, errors.Errorf("%s: column %q is a group of %d columns", p.path, name, len(leaves))
+	}
+
+	leaf, _ := p.pq.Schema().Lookup(leaves[0]...)
+	return parquetColumn{name: name, index: leaf.ColumnIndex, repeated: leaf.MaxRepetitionLevel > 0}, nil
+}
+
+func (p *parquetFile) numRows() int {
+	return int(p.pq.NumRows())
+}
+
+// Read count rows starting at row start, rows may span row groups
+func (p *parquetFile) readRows(start, count int, fn func(row parquet.Row)) error {
+	base := 0
+	for _, rowGroup := range p.pq.RowGroups() {
+		groupRows := int(rowGroup.NumRows())
+		if base+groupRows <= start {
+			base += groupRows
+			continue
+		}
+		if count <= 0 {
+			return nil
+		}
+
+		rows := rowGroup.Rows()
+		if err := rows.SeekToRow(int64(start - base)); err != nil {
+			rows.Close()
+			return errors.Wrapf(err, "%s: seek to row %d", p.path, start)
+		}
+
+		buf := make([]parquet.Row, min(count, parquetReadRows))
+		for count > 0 && start < base+groupRows {
+			n, err := rows.ReadRows(buf[:min(len(buf), count)])
+			for _, row := range buf[:n] {
+				fn(row)
+			}
+			start += n
+			count -= n
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				rows.Close()
+				return errors.Wrapf(err, "%s: read rows", p.path)
+			}
+		}
+		rows.Close()
+		base += groupRows
+	}
+
+	if count > 0 {
+		return errors.Errorf("%s: %d rows past the end of the file", p.path, count)
+	}
+	return nil
+}
+
+// Read the vectors in column of count rows starting at row start
+func (p *parquetFile) vectors(column parquetColumn, start, count int) ([][]float32, error) {
+	out := make([][]float32, 0, count)
+	var convErr error
+	err := p.readRows(start, count, func(row parquet.Row) {
+		vector, err := parquetVector(row, column)
+		if err != nil && convErr == nil {
+			convErr = errors.Wrap(err, p.path)
+		}
+		out = append(out, vector)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, convErr
+}
+
+// The vector of a row, integer components are converted to float32
+func parquetVector(row parquet.Row, column parquetColumn) ([]float32, error) {
+	vector := []float32{}
+	for _, v := range row {
+		if v.Column() != column.index || v.IsNull() {
+			continue
+		}
+		switch v.Kind() {
+		case parquet.Float:
+			vector = append(vector, v.Float())
+		case parquet.Double:
+			vector = append(vector, float32(v.Double()))
+		case parquet.Int32:
+			vector = append(vector, float32(v.Int32()))
+		case parquet.Int64:
+			vector = append(vector, float32(v.Int64()))
+		default:
+			return nil, errors.Errorf("column %q has unsupported vector type %s", column.name, v.Kind())
+		}
+	}
+	return vector, nil
+}
+
+// The values of columns in a row as object properties, lists become arrays
+// and nulls are left out
+func parquetProperties(row parquet.Row, columns []parquetColumn) map[string]interface{} {
+	props := map[string]interface{}{}
+	for _, column := range columns {
+		var list []interface{}
+		for _, v := range row {
+			if v.Column() != column.index || v.IsNull() {
+				continue
+			}
+			if !column.repeated {
+				props[column.name] = parquetValue(v)
+				break
+			}
+			list = append(list, parquetValue(v))
+		}
+		if list != nil {
+			props[column.name] = list
+		}
+	}
+	return props
+}
+
+func parquetValue(v parquet.Value) interface{} {
+	switch v.Kind() {
+	case parquet.Boolean:
+		return v.Boolean()
+	case parquet.Int32:
+		return int64(v.Int32())
+	case parquet.Int64:
+		return v.Int64()
+	case parquet.Float:
+		return float64(v.Float())
+	case parquet.Double:
+		return v.Double()
+	default:
+		return v.String()
+	}
+}
+
+func (p *parquetFile) Close() {
+	p.file.Close()
+}
+
+// A Parquet dataset, the train vectors and properties are streamed row group
+// by row group from cfg.BenchmarkFile. Test vectors are read from the same
+// column of --testVectors, ground truth from the neighbors column of
+// --neighbors.
+type parquetSource struct {
+	train         *parquetFile
+	vector        parquetColumn
+	properties    []parquetColumn
+	testPath      string
+	neighborsPath string
+	dimensions    int
+}
+
+func openParquetSource(cfg *Config) *parquetSource {
+	if cfg.MultiVectorDimensions > 0 {
+		log.Fatalf("multi-vector datasets are only supported in hdf5 format")
+	}
+
+	train, err := openParquetFile(cfg.BenchmarkFile)
+	if err != nil {
+		log.Fatalf("Error opening dataset: %v", err)
+	}
+
+	s := &parquetSource{
+		train:         train,
+		testPath:      cfg.TestVectorsFile,
+		neighborsPath: cfg.NeighborsFile,
+	}
+
+	s.vector, err = train.column(cfg.VectorColumn)
+	if err != nil {
+		log.Fatalf("Error opening dataset, set --vectorColumn: %v", err)
+	}
+	for _, name := range cfg.PropertyColumns {
+		column, err := train.column(name)
+		if err != nil {
+			log.Fatalf("Error opening dataset: %v", err)
+		}
+		s.properties = append(s.properties, column)
+	}
+
+	if train.numRows() > 0 {
+		first, err := train.vectors(s.vector, 0, 1)
+		if err != nil {
+			log.Fatalf("Error reading dataset: %v", err)
+		}
+		s.dimensions = len(first[0])
+	}
+
+	return s
+}
+
+func (s *parquetSource) trainExtent(cfg *Config) (uint, uint) {
+	return uint(s.train.numRows()), uint(s.dimensions)
+}
+
+func (s *parquetSource) streamTrain(chunks chan<- Batch, cfg *Config, startOffset uint, maxRecords uint, filters []int) {
+	rows := uint(s.train.numRows())
+
+	i := uint(0)
+	if maxRecords != 0 && maxRecords < rows {
+		rows = maxRecords
+	}
+
+	if startOffset != 0 && i < rows {
+		i = startOffset
+	}
+
+	batchSize := uint(cfg.BatchSize)
+
+	log.WithFields(log.Fields{"rows": rows, "dimensions": s.dimensions, "properties": cfg.PropertyColumns}).Printf(
+		"Reading Parquet dataset")
+
+	if i >= rows {
+		return
+	}
+
+	// The rows are read in one pass so every row group is decoded once,
+	// seeking per batch would decode compressed pages again and again
+	batch := Batch{Offset: int(i), Filters: []int{}}
+	err := s.train.readRows(int(i), int(rows-i), func(row parquet.Row) {
+		vector, err := parquetVector(row, s.vector)
+		if err != nil {
+			log.Fatalf("Error reading dataset: %v", err)
+		}
+		batch.Vectors = append(batch.Vectors, vector)
+		if len(s.properties) > 0 {
+			batch.Properties = append(batch.Properties, parquetProperties(row, s.properties))
+		}
+		if uint(len(batch.Vectors)) == batchSize {
+			chunks <- batch
+			batch = Batch{Offset: batch.Offset + len(batch.Vectors), Filters: []int{}}
+		}
+	})
+	if err != nil {
+		log.Fatalf("Error reading dataset: %v", err)
+	}
+	if len(batch.Vectors) > 0 {
+		chunks <- batch
+	}
+}
+
+func (s *parquetSource) openTest() *parquetFile {
+	if s.testPath == "" {
+		log.Fatalf("--testVectors must be set for Parquet datasets")
+	}
+	test, err := openParquetFile(s.testPath)
+	if err != nil {
+		log.Fatalf("Error opening test vectors: %v", err)
+	}
+	return test
+}
+
+func (s *parquetSource) testCount(cfg *Config) int {
+	test := s.openTest()
+	defer test.Close()
+	return test.numRows()
+}
+
+func (s *parquetSource) testVectors(cfg *Config, rows []int) [][]float32 {
+	test := s.openTest()
+	defer test.Close()
+
+	column, err := test.column(cfg.VectorColumn)
+	if err != nil {
+		log.Fatalf("Error opening test vectors: %v", err)
+	}
+
+	vectors, err := readRowRuns(rows, test.numRows(), func(start, count int) ([][]float32, error) {
+		return test.vectors(column, start, count)
+	})
+	if err != nil {
+		log.Fatalf("Error reading test vectors: %v", err)
+	}
+	if len(vectors) > 0 && len(vectors[0]) != s.dimensions {
+		log.Fatalf("Test vectors have %d dimensions, base vectors %d", len(vectors[0]), s.dimensions)
+	}
+	return vectors
+}
+
+func (s *parquetSource) neighbors(cfg *Config, rows []int) [][]int {
+	if s.neighborsPath == "" {
+		log.Fatalf("--neighbors must be set for Parquet datasets")
+	}
+	gt, err := openParquetFile(s.neighborsPath)
+	if err != nil {
+		log.Fatalf("Error opening neighbors: %v", err)
+	}
+	defer gt.Close()
+
+	column, err := gt.column("neighbors")
+	if err != nil {
+		log.Fatalf("Error opening neighbors: %v", err)
+	}
+
+	neighbors, err := readRowRuns(rows, gt.numRows(), func(start, count int) ([][]int, error) {
+		out := make([][]int, 0, count)
+		err := gt.readRows(start, count, func(row parquet.Row) {
+			ids := []int{}
+			for _, v := range row {
+				if v.Column() != column.index || v.IsNull() {
+					continue
+				}
+				if v.Kind() == parquet.Int32 {
+					ids = append(ids, int(v.Int32()))
+				} else {
+					ids = append(ids, int(v.Int64()))
+				}
+			}
+			out = append(out, ids)
+		})
+		return out, err
+	})
+	if err != nil {
+		log.Fatalf("Error reading neighbors: %v", err)
+	}
+	return neighbors
+}
+
+func (s *parquetSource) distances(cfg *Config, rows []int) [][]float32 {
+	return nil
+}
+
+func (s *parquetSource) categories(name string) []int {
+	log.Fatalf("Parquet datasets have no %s, import the filter columns with --propertyColumns instead", name)
+	return nil
+}
+
+func (s *parquetSource) Close() {
+	s.train.Close()
+}
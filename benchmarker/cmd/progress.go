@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+)
+
+// On a terminal the status line is redrawn every second, otherwise a plain
+// log line is written every 10 seconds
+const (
+	progressTTYInterval  = time.Second
+	progressLogInterval  = 10 * time.Second
+	progressQueueRefresh = 2 * time.Second
+	progressQueryWindow  = 5
+)
+
+// Phase and ef of the running benchmark, shown in the progress output and
+// exposed as a metric
+var runPhase struct {
+	sync.Mutex
+	name string
+	ef   int
+}
+
+func setPhase(name string, ef int) {
+	runPhase.Lock()
+	defer runPhase.Unlock()
+	runPhase.name = name
+	runPhase.ef = ef
+	benchmarkerMetrics.setPhase(name, ef)
+}
+
+func currentPhase() (string, int) {
+	runPhase.Lock()
+	defer runPhase.Unlock()
+	return runPhase.name, runPhase.ef
+}
+
+func stdoutIsTTY() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Periodically reports the status returned by report until stopped
+type progressReporter struct {
+	tty    bool
+	report func() (string, log.Fields)
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func startProgress(report func() (string, log.Fields)) *progressReporter {
+	p := &progressReporter{
+		tty:    stdoutIsTTY(),
+		report: report,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	interval := progressLogInterval
+	if p.tty {
+		interval = progressTTYInterval
+	}
+
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				if p.tty {
+					// clear the status line so regular output starts on a clean line
+					fmt.Fprint(os.Stdout, "\r\033[K")
+				}
+				return
+			case <-ticker.C:
+				p.print()
+			}
+		}
+	}()
+
+	return p
+}
+
+func (p *progressReporter) print() {
+	msg, fields := p.report()
+	if !p.tty {
+		log.WithFields(fields).Info(msg)
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b := strings.Builder{}
+	b.WriteString(msg)
+	for _, k := range keys {
+		b.WriteString(fmt.Sprintf("  %s=%v", k, fields[k]))
+	}
+	fmt.Fprintf(os.Stdout, "\r\033[K%s", b.String())
+}
+
+func (p *progressReporter) Stop() {
+	close(p.stop)
+	<-p.done
+}
+
+// Tracks the rows of an import, the vector queue length is polled in the
+// background with the same nodes status call as waitReady
+type importProgress struct {
+	total    int64
+	imported atomic.Int64
+	queue    atomic.Int64
+	start    time.Time
+
+	lastCount int64
+	lastTime  time.Time
+
+	stopPoll chan struct{}
+	reporter *progressReporter
+}
+
+func startImportProgress(cfg *Config, client *weaviate.Client, total int64) *importProgress {
+	p := &importProgress{
+		total:    total,
+		start:    time.Now(),
+		lastTime: time.Now(),
+		stopPoll: make(chan struct{}),
+	}
+	p.queue.Store(-1)
+
+	go func() {
+		for {
+			if length, err := vectorQueueLength(cfg, client); err != nil {
+				log.Debugf("Error polling vector queue length: %v", err)
+			} else {
+				p.queue.Store(length)
+			}
+
+			select {
+			case <-p.stopPoll:
+				return
+			case <-time.After(progressQueueRefresh):
+			}
+		}
+	}()
+
+	p.reporter = startProgress(p.status)
+	return p
+}
+
+func (p *importProgress) add(rows int) {
+	p.imported.Add(int64(rows))
+}
+
+func (p *importProgress) status() (string, log.Fields) {
+	now := time.Now()
+	imported := p.imported.Load()
+
+	rate := float64(imported-p.lastCount) / now.Sub(p.lastTime).Seconds()
+	p.lastCount = imported
+	p.lastTime = now
+
+	fields := log.Fields{
+		"rows":   fmt.Sprintf("%d/%d", imported, p.total),
+		"rows/s": fmt.Sprintf("%.0f", rate),
+	}
+
+	// The ETA is based on the average rate since the start of the import
+	if avg := float64(imported) / now.Sub(p.start).Seconds(); avg > 0 && imported < p.total {
+		fields["eta"] = (time.Duration(float64(p.total-imported)/avg) * time.Second).Round(time.Second)
+	}
+	if queue := p.queue.Load(); queue >= 0 {
+		fields["queue"] = queue
+	}
+
+	return "Importing", fields
+}
+
+func (p *importProgress) Stop() {
+	close(p.stopPoll)
+	p.reporter.Stop()
+}
+
+// Reports the QPS, p99 and recall of the last few complete intervals of a
+// query run
+func startQueryProgress(tl *timeline) *progressReporter {
+	return startProgress(func() (string, log.Fields) {
+		_, ef := currentPhase()
+		record := tl.window(progressQueryWindow)
+
+		fields := log.Fields{
+			"qps":    fmt.Sprintf("%.1f", record.QPS),
+			"p99":    time.Duration(record.P99 * float64(time.Second)),
+			"recall": fmt.Sprintf("%.4f", record.Recall),
+		}
+		if record.Errors > 0 {
+			fields["errors"] = record.Errors
+		}
+		if ef > 0 {
+			fields["ef"] = ef
+		}
+
+		return "Querying", fields
+	})
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var qpsSweepCommand = &cobra.Command{
+	Use:   "qps-sweep",
+	Short: "Find the maximum sustainable QPS of an imported ANN dataset",
+	Long: `Step up the offered load (or concurrency) against a collection that was already imported with ann-benchmark,
+until p99 latency passes --maxP99 or errors show up. Every step is written as one result record to ./results/<runID>.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := globalConfig
+		cfg.Mode = "qps-sweep"
+
+		if err := cfg.Validate(); err != nil {
+			fatal(err)
+		}
+
+		cfg.parseLabels()
+
+		source := openDatasetSource(&cfg)
+		defer source.Close()
+
+		test := loadQueries(source, &cfg)
+
+		runQPSSweep(&cfg, test)
+	},
+}
+
+func initQPSSweep() {
+	rootCmd.AddCommand(qpsSweepCommand)
+	addDatasetFlags(qpsSweepCommand)
+
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.Labels,
+		"labels", "", "Labels of format key1=value1,key2=value2,...")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.NeighborsFile,
+		"neighbors", "", "Path to the ground truth: .ivecs, .ibin, .parquet (neighbors column) or an hdf5 file with a neighbors dataset (TEXMEX default <name>_groundtruth.ivecs next to <name>_base)")
+	qpsSweepCommand.PersistentFlags().StringVarP(&globalConfig.ClassName,
+		"className", "c", "Vector", "Class name for testing")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.NamedVector,
+		"namedVector", "", "Named vector")
+	qpsSweepCommand.PersistentFlags().IntVarP(&globalConfig.MultiVectorDimensions,
+		"multiVector", "m", 0, "Enable multi-dimensional vectors with the specified number of dimensions")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.IndexType,
+		"indexType", "hnsw", "Index type (hnsw or flat)")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.EfArray,
+		"efArray", "16,32,64,128,256", "Array of ef parameters as comma separated list")
+	qpsSweepCommand.PersistentFlags().IntVarP(&globalConfig.Limit,
+		"limit", "l", 10, "Set the query limit / k (default 10)")
+	qpsSweepCommand.PersistentFlags().BoolVar(&globalConfig.Filter,
+		"filter", false, "Whether to use filtering for the dataset (default false)")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.FilterSpec,
+		"filterSpec", "", "JSON file with a where filter in the REST API format, placeholders like \"{{price}}\" are filled with the values of each test row")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.TestPropertiesFile,
+		"testProperties", "", "JSON lines file with the filter values of every test vector, e.g. {\"price\": 10}")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.QueryType,
+		"queryType", "nearVector", "Type of the test queries (nearVector or hybrid)")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.QueryTextsFile,
+		"queryTexts", "", "Text file with the keyword query of every test vector, one per line, for hybrid queries")
+	qpsSweepCommand.PersistentFlags().StringSliceVar(&globalConfig.QueryProperties,
+		"queryProperties", nil, "Properties searched by the keyword part of hybrid queries (default all text properties)")
+	qpsSweepCommand.PersistentFlags().Float64Var(&globalConfig.Alpha,
+		"alpha", 0.75, "Weight of the vector search in hybrid queries, 0 is pure keyword and 1 pure vector search")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.FusionType,
+		"fusionType", "relativeScore", "Fusion of keyword and vector results in hybrid queries (relativeScore or ranked)")
+	qpsSweepCommand.PersistentFlags().Float64Var(&globalConfig.RecallEpsilon,
+		"recallEpsilon", 1e-3, "Results at most this much farther than the k-th neighbor count for recall, if the dataset has distances")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.QrelsFile,
+		"qrels", "", "BEIR qrels .tsv with the graded relevance of train rows (corpus-id) for test rows (query-id), enables nDCG, MRR, MAP and precision")
+	qpsSweepCommand.PersistentFlags().IntVar(&globalConfig.MaxQueries,
+		"maxQueries", 0, "Only read and query the first n test vectors, or a random sample of n with --sampleQueries (default 0, all)")
+	qpsSweepCommand.PersistentFlags().BoolVar(&globalConfig.SampleQueries,
+		"sampleQueries", false, "Query a random sample of --maxQueries test vectors instead of the first ones")
+	qpsSweepCommand.PersistentFlags().Int64Var(&globalConfig.Seed,
+		"seed", 0, "Seed of the --sampleQueries sample")
+	qpsSweepCommand.PersistentFlags().IntVarP(&globalConfig.Parallel,
+		"parallel", "p", 64, "Maximum number of in-flight queries when sweeping by qps")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.Arrival,
+		"arrival", "fixed", "Arrival distribution of queries when sweeping by qps (fixed or poisson)")
+	qpsSweepCommand.PersistentFlags().Float64Var(&globalConfig.MaxErrorRate,
+		"maxErrorRate", 1, "Abort once the share of failed queries passes this rate, failed steps already end the sweep (default 1, never abort)")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.SweepBy,
+		"sweepBy", "qps", "Step up the offered load (qps) or the number of closed-loop workers (parallel)")
+	qpsSweepCommand.PersistentFlags().Float64Var(&globalConfig.SweepStart,
+		"sweepStart", 100, "Offered QPS or parallelism of the first step")
+	qpsSweepCommand.PersistentFlags().Float64Var(&globalConfig.SweepFactor,
+		"sweepFactor", 1.5, "Factor the offered QPS or parallelism is multiplied by on every step")
+	qpsSweepCommand.PersistentFlags().IntVar(&globalConfig.SweepSteps,
+		"sweepSteps", 20, "Maximum number of steps per ef")
+	qpsSweepCommand.PersistentFlags().IntVar(&globalConfig.SweepStepDuration,
+		"sweepStepDuration", 30, "Duration of a step in seconds when sweeping by qps, the test set is repeated as needed")
+	qpsSweepCommand.PersistentFlags().Float64Var(&globalConfig.MaxP99,
+		"maxP99", 100, "Stop the sweep once the p99 latency passes this threshold in milliseconds")
+	qpsSweepCommand.PersistentFlags().StringVarP(&globalConfig.API,
+		"api", "a", "grpc", "The API to use on benchmarks")
+	qpsSweepCommand.PersistentFlags().StringVarP(&globalConfig.Origin,
+		"grpcOrigin", "u", "localhost:50051", "The gRPC origin that Weaviate is running at")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.HttpOrigin,
+		"httpOrigin", "localhost:8080", "The http origin for Weaviate (only used if grpc enabled)")
+	qpsSweepCommand.PersistentFlags().StringVar(&globalConfig.HttpScheme,
+		"httpScheme", "http", "The http scheme (http or https)")
+}
+
+// Run one sweep per ef and log the maximum sustainable QPS of each
+func runQPSSweep(cfg *Config, test *testSet) {
+	runID := strconv.FormatInt(time.Now().Unix(), 10)
+
+	efCandidates, err := parseEfValues(cfg.EfArray)
+	if err != nil {
+		log.Fatalf("Error parsing efArray, expected commas separated format \"16,32,64\" but:%v\n", err)
+	}
+
+	client := createClient(cfg)
+	maxP99 := time.Duration(cfg.MaxP99 * float64(time.Millisecond))
+
+	var benchmarkResultsMap []map[string]interface{}
+	for _, ef := range efCandidates {
+		updateEf(ef, cfg, client)
+		setPhase("query", ef)
+
+		maxSustainable := 0.0
+		load := cfg.SweepStart
+		for step := 0; step < cfg.SweepSteps; step++ {
+			stepCfg := *cfg
+			if cfg.SweepBy == "qps" {
+				stepCfg.TargetQPS = load
+				stepCfg.Queries = int(math.Ceil(load * float64(cfg.SweepStepDuration)))
+			} else {
+				stepCfg.Parallel = int(math.Round(load))
+				stepCfg.Queries = len(test.vectors)
+			}
+
+			result := benchmark(stepCfg, annQueryFn(&stepCfg, test))
+			p99 := result.Percentile(99)
+
+			sustainable := p99 <= maxP99 && result.Failed == 0
+			if cfg.SweepBy == "qps" && result.QueriesPerSecond < 0.9*load {
+				// the server fell behind the offered load
+				sustainable = false
+			}
+
+			log.WithFields(log.Fields{
+				"ef": ef, "step": step, "targetQPS": stepCfg.TargetQPS, "parallel": stepCfg.Parallel,
+				"qps": result.QueriesPerSecond, "p99": p99, "recall": result.Recall,
+				"failed": result.Failed, "sustainable": sustainable,
+			}).Info("Sweep step")
+
+			benchResult := newResultsJSONBenchmark(&stepCfg, ef, result, 0, runID, &Memstats{})
+			resultMap := benchResult.toMap(cfg)
+			resultMap["sweepBy"] = cfg.SweepBy
+			resultMap["sweepStep"] = step
+			resultMap["sustainable"] = sustainable
+			benchmarkResultsMap = append(benchmarkResultsMap, resultMap)
+			// Rewritten after every step, so the completed steps are kept if a
+			// later step or ef change exits on an error
+			writeBenchmarkResults(runID, benchmarkResultsMap)
+
+			if !sustainable {
+				break
+			}
+
+			maxSustainable = result.QueriesPerSecond
+			next := load * cfg.SweepFactor
+			if cfg.SweepBy == "parallel" && math.Round(next) <= math.Round(load) {
+				next = math.Round(load) + 1
+			}
+			load = next
+		}
+
+		log.WithFields(log.Fields{"ef": ef, "maxSustainableQPS": maxSustainable,
+			"maxP99": maxP99}).Info("Sweep result")
+	}
+}
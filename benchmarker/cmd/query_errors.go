@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/status"
+)
+
+// The error rate is only checked against --maxErrorRate once this many
+// queries were sent, so a single early failure does not abort a run
+const errorBudgetMinQueries = 100
+
+// A failed query, class is the gRPC status code or http status the failure
+// is counted under
+type queryError struct {
+	class string
+	err   error
+}
+
+func (e *queryError) Error() string {
+	return fmt.Sprintf("%s: %v", e.class, e.err)
+}
+
+func (e *queryError) Unwrap() error {
+	return e.err
+}
+
+// Classify a failed gRPC call by its status code, e.g. Unavailable
+func grpcQueryError(err error) *queryError {
+	return &queryError{class: status.Code(err).String(), err: err}
+}
+
+// Classify a failed http request, transport errors carry no status
+func httpQueryError(statusCode int, err error) *queryError {
+	if statusCode > 0 {
+		return &queryError{class: fmt.Sprintf("http_%d", statusCode), err: err}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &queryError{class: "timeout", err: err}
+	}
+	return &queryError{class: "transport", err: err}
+}
+
+func errorClass(err error) string {
+	var qe *queryError
+	if errors.As(err, &qe) {
+		return qe.class
+	}
+	return "unknown"
+}
+
+// Shared by all workers of a run, aborts the run once the share of failed
+// queries passes cfg.MaxErrorRate while it is running
+type errorBudget struct {
+	maxRate float64
+	sent    atomic.Int64
+	failed  atomic.Int64
+}
+
+func newErrorBudget(cfg *Config) *errorBudget {
+	return &errorBudget{maxRate: cfg.MaxErrorRate}
+}
+
+func (b *errorBudget) success() {
+	b.sent.Add(1)
+}
+
+func (b *errorBudget) failure(err error) {
+	sent := b.sent.Add(1)
+	failed := b.failed.Add(1)
+	if sent >= errorBudgetMinQueries && b.exceeded(sent, failed) {
+		log.Fatalf("Aborting, %d of %d queries failed which exceeds --maxErrorRate %v, last error: %v",
+			failed, sent, b.maxRate, err)
+	}
+}
+
+// Check the error rate at the end of a run, also for runs with fewer queries
+// than errorBudgetMinQueries. The results of a finished run are kept, so
+// this only reports whether the budget was exceeded.
+func (b *errorBudget) finish() bool {
+	sent, failed := b.sent.Load(), b.failed.Load()
+	if !b.exceeded(sent, failed) {
+		return false
+	}
+	log.Warnf("%d of %d queries failed which exceeds --maxErrorRate %v", failed, sent, b.maxRate)
+	return true
+}
+
+func (b *errorBudget) exceeded(sent, failed int64) bool {
+	return sent > 0 && float64(failed)/float64(sent) > b.maxRate
+}
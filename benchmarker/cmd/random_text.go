@@ -11,6 +11,7 @@ import (
 
 func initRandomText() {
 	rootCmd.AddCommand(randomTextCmd)
+	addQueryLoadFlags(randomTextCmd)
 	randomTextCmd.PersistentFlags().IntVarP(&globalConfig.Queries,
 		"queries-file", "f", 100, "Set the number of queries the benchmarker should run")
 	randomTextCmd.PersistentFlags().IntVarP(&globalConfig.Parallel,
@@ -9,7 +9,6 @@ import (
 	"math/rand"
 	"os"
 	"runtime"
-	"strconv"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -23,6 +22,7 @@ import (
 
 func initRandomVectors() {
 	rootCmd.AddCommand(randomVectorsCmd)
+	addQueryLoadFlags(randomVectorsCmd)
 	numCPU := runtime.NumCPU()
 	randomVectorsCmd.PersistentFlags().IntVarP(&globalConfig.Queries,
 		"queries", "q", 100, "Set the number of queries the benchmarker should run")
@@ -38,6 +38,8 @@ func initRandomVectors() {
 		"dimensions", "d", 0, "Set the vector dimensions (will infer from class if not set)")
 	randomVectorsCmd.PersistentFlags().StringVarP(&globalConfig.ClassName,
 		"className", "c", "", "The Weaviate class to run the benchmark against")
+	randomVectorsCmd.PersistentFlags().StringVar(&globalConfig.WhereFilter,
+		"where", "", "An entire where filter as a string, on grpc also as JSON or the path of a JSON file")
 	randomVectorsCmd.PersistentFlags().StringVarP(&globalConfig.Origin,
 		"grpcOrigin", "u", "localhost:50051", "The gRPC origin that Weaviate is running at")
 	randomVectorsCmd.PersistentFlags().StringVar(&globalConfig.HttpOrigin,
@@ -135,33 +137,70 @@ func encodeVector(fs []float32) []byte {
 	return buf
 }
 
-func nearVectorQueryGrpc(cfg *Config, vec []float32, tenant string, filter int) []byte {
+func nearVectorQueryGrpc(cfg *Config, vec []float32, tenant string, filters *weaviategrpc.Filters) []byte {
+	return marshalSearchRequest(nearVectorSearchRequest(cfg, vec, tenant, filters))
+}
+
+// A hybrid search for text and vec, the vector is used as is instead of
+// being vectorized from the text
+func hybridQueryGrpc(cfg *Config, vec []float32, text string, tenant string, filters *weaviategrpc.Filters) []byte {
+	searchRequest := nearVectorSearchRequest(cfg, vec, tenant, filters)
+	searchRequest.NearVector = nil
+	searchRequest.Metadata.Distance = false
+	searchRequest.Metadata.Score = true
+
+	hybrid := &weaviategrpc.Hybrid{
+		Query:      text,
+		Properties: cfg.QueryProperties,
+		Alpha:      float32(cfg.Alpha),
+		FusionType: weaviategrpc.Hybrid_FUSION_TYPE_RELATIVE_SCORE,
+	}
+	if cfg.FusionType == "ranked" {
+		hybrid.FusionType = weaviategrpc.Hybrid_FUSION_TYPE_RANKED
+	}
 
-	var searchRequest *weaviategrpc.SearchRequest
 	if cfg.MultiVectorDimensions > 0 {
+		hybrid.Vectors = multiVectors(cfg, vec)
+	} else {
+		hybrid.VectorBytes = encodeVector(vec)
+	}
+	if cfg.NamedVector != "" {
+		hybrid.Targets = &weaviategrpc.Targets{TargetVectors: []string{cfg.NamedVector}}
+	}
 
-		rows := len(vec) / cfg.MultiVectorDimensions
-		doc := make([][]float32, rows)
-		for i := 0; i < rows; i++ {
-			start := i * cfg.MultiVectorDimensions
-			end := start + cfg.MultiVectorDimensions
-			doc[i] = vec[start:end]
-		}
-		multiVec := []*weaviategrpc.Vectors{{
-			Name:        "multivector",
-			VectorBytes: byteops.Fp32SliceOfSlicesToBytes(doc),
-			Type:        weaviategrpc.Vectors_VECTOR_TYPE_MULTI_FP32,
-		}}
+	searchRequest.HybridSearch = hybrid
+	return marshalSearchRequest(searchRequest)
+}
 
+// Split a flattened multi-vector into vectors of cfg.MultiVectorDimensions
+func multiVectors(cfg *Config, vec []float32) []*weaviategrpc.Vectors {
+	rows := len(vec) / cfg.MultiVectorDimensions
+	doc := make([][]float32, rows)
+	for i := 0; i < rows; i++ {
+		start := i * cfg.MultiVectorDimensions
+		end := start + cfg.MultiVectorDimensions
+		doc[i] = vec[start:end]
+	}
+	return []*weaviategrpc.Vectors{{
+		Name:        "multivector",
+		VectorBytes: byteops.Fp32SliceOfSlicesToBytes(doc),
+		Type:        weaviategrpc.Vectors_VECTOR_TYPE_MULTI_FP32,
+	}}
+}
+
+// A nearVector search, filters may be nil
+func nearVectorSearchRequest(cfg *Config, vec []float32, tenant string, filters *weaviategrpc.Filters) *weaviategrpc.SearchRequest {
+	var searchRequest *weaviategrpc.SearchRequest
+	if cfg.MultiVectorDimensions > 0 {
 		searchRequest = &weaviategrpc.SearchRequest{
 			Collection: cfg.ClassName,
 			Limit:      uint32(cfg.Limit),
 			NearVector: &weaviategrpc.NearVector{
-				Vectors: multiVec,
+				Vectors: multiVectors(cfg, vec),
 			},
 			Metadata: &weaviategrpc.MetadataRequest{
 				Certainty: false,
-				Distance:  false,
+				Distance:  true,
 				Uuid:      true,
 			},
 		}
@@ -175,7 +214,7 @@ func nearVectorQueryGrpc(cfg *Config, vec []float32, tenant string, filter int)
 			},
 			Metadata: &weaviategrpc.MetadataRequest{
 				Certainty: false,
-				Distance:  false,
+				Distance:  true,
 				Uuid:      true,
 			},
 		}
@@ -196,17 +235,12 @@ func nearVectorQueryGrpc(cfg *Config, vec []float32, tenant string, filter int)
 		}
 	}
 
-	if filter >= 0 {
-		searchRequest.Filters = &weaviategrpc.Filters{
-			TestValue: &weaviategrpc.Filters_ValueText{
-				ValueText: strconv.Itoa(filter),
-			},
-			On:       []string{"category"},
-			Operator: weaviategrpc.Filters_OPERATOR_EQUAL,
-		}
+	searchRequest.Filters = filters
 
-	}
+	return searchRequest
+}
 
+func marshalSearchRequest(searchRequest *weaviategrpc.SearchRequest) []byte {
 	data, err := proto.Marshal(searchRequest)
 	if err != nil {
 		fmt.Printf("grpc marshal err: %v\n", err)
@@ -216,6 +250,7 @@ func nearVectorQueryGrpc(cfg *Config, vec []float32, tenant string, filter int)
 }
 
 func benchmarkNearVector(cfg Config) Results {
+	filters := grpcWhereFilter(&cfg)
 	return benchmark(cfg, func(className string) QueryWithNeighbors {
 		if cfg.API == "graphql" {
 			return QueryWithNeighbors{
@@ -224,7 +259,7 @@ func benchmarkNearVector(cfg Config) Results {
 		}
 		if cfg.API == "grpc" {
 			return QueryWithNeighbors{
-				Query: nearVectorQueryGrpc(&cfg, randomVector(cfg.Dimensions), cfg.Tenant, -1),
+				Query: nearVectorQueryGrpc(&cfg, randomVector(cfg.Dimensions), cfg.Tenant, filters),
 			}
 		}
 
@@ -258,11 +293,7 @@ func benchmarkNearVectorDuration(cfg Config) Results {
 	medianResult.Mean = time.Duration(median(samples.Mean))
 	medianResult.Took = time.Duration(median(samples.Took))
 	medianResult.QueriesPerSecond = median(samples.QueriesPerSecond)
-	medianResult.Percentiles = results.Percentiles
-	medianResult.PercentilesLabels = results.PercentilesLabels
-	medianResult.Total = results.Total
-	medianResult.Successful = results.Successful
-	medianResult.Failed = results.Failed
+	samples.mergeCounts(&medianResult)
 	medianResult.Parallelization = cfg.Parallel
 
 	log.WithFields(log.Fields{"iterations": iterations}).Infof("Queried for %d seconds", cfg.QueryDuration)
@@ -55,6 +55,7 @@ var rawCmd = &cobra.Command{
 
 func initRaw() {
 	rootCmd.AddCommand(rawCmd)
+	addQueryLoadFlags(rawCmd)
 	rawCmd.PersistentFlags().StringVarP(&globalConfig.QueriesFile,
 		"queries", "q", "", "Point to the queries file, (.txt)")
 	rawCmd.PersistentFlags().IntVarP(&globalConfig.Parallel,
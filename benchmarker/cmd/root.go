@@ -28,11 +28,19 @@ func init() {
 		log.SetLevel(log.InfoLevel)
 	}
 
+	rootCmd.PersistentFlags().StringVar(&globalConfig.MetricsListen,
+		"metricsListen", "", "Address to serve the benchmarker's own Prometheus metrics on while running, e.g. :9100")
+
 	initRandomVectors()
 	initRandomText()
 	initDataset()
 	initRaw()
 	initAnnBenchmark()
+	initQPSSweep()
+	initBM25()
+	initSelectivitySweep()
+	initGroundTruth()
+	initGenerateDataset()
 	initColbert()
 }
 
@@ -40,6 +48,14 @@ var rootCmd = &cobra.Command{
 	Use:   "benchmarker",
 	Short: "Weaviate Benchmarker",
 	Long:  `A Weaviate Benchmarker`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := applyFlagDefaults(cmd); err != nil {
+			fatal(err)
+		}
+		if globalConfig.MetricsListen != "" {
+			startMetricsServer(globalConfig.MetricsListen)
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Printf("running the root command, see help or -h for available commands\n")
 	},
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	weaviategrpc "github.com/weaviate/weaviate/grpc/generated/protocol/v1"
+)
+
+var selectivitySweepCommand = &cobra.Command{
+	Use:   "selectivity-sweep",
+	Short: "Benchmark filtered queries of an ANN dataset at a ladder of filter selectivities",
+	Long: `Import the train vectors of a dataset with one synthetic text property per selectivity, property
+cardinality<n> holds a pseudo-random value between 0 and n-1 with n = 1/selectivity. Every test vector is then
+queried with an equality filter on each of these properties against the exact filtered ground truth, which is
+computed by brute force before querying. The QPS and recall of every selectivity and ef are written to
+./results/<runID>.json and printed as a matrix, e.g. to compare --filterStrategy sweeping and acorn.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := globalConfig
+		cfg.Mode = "selectivity-sweep"
+		cfg.QueryType = "nearVector"
+
+		if err := cfg.Validate(); err != nil {
+			fatal(err)
+		}
+
+		cfg.parseLabels()
+		cfg.SelectivityCardinalities, _ = parseSelectivities(cfg.Selectivities)
+
+		source := openDatasetSource(&cfg)
+		defer source.Close()
+
+		client := createClient(&cfg)
+
+		if !cfg.QueryOnly {
+			createSchema(&cfg, client)
+
+			log.WithFields(log.Fields{
+				"index": cfg.IndexType, "filterStrategy": cfg.FilterStrategy,
+				"cardinalities": cfg.SelectivityCardinalities, "dataset": cfg.BenchmarkFile,
+			}).Info("Starting import")
+			loadANNBenchmarksFile(source, &cfg, client, 0)
+		}
+
+		levels := loadSelectivityLevels(source, &cfg)
+		runSelectivitySweep(&cfg, levels)
+	},
+}
+
+func initSelectivitySweep() {
+	rootCmd.AddCommand(selectivitySweepCommand)
+	addDatasetFlags(selectivitySweepCommand)
+	addQueryLoadFlags(selectivitySweepCommand)
+
+	numCPU := runtime.NumCPU()
+
+	selectivitySweepCommand.PersistentFlags().StringVar(&globalConfig.Selectivities,
+		"selectivities", "0.001,0.01,0.1,0.5", "Comma separated share of the objects that pass the filter of each step")
+	selectivitySweepCommand.PersistentFlags().StringVar(&globalConfig.Labels,
+		"labels", "", "Labels of format key1=value1,key2=value2,...")
+	selectivitySweepCommand.PersistentFlags().StringVarP(&globalConfig.ClassName,
+		"className", "c", "Vector", "Class name for testing")
+	selectivitySweepCommand.PersistentFlags().StringVarP(&globalConfig.DistanceMetric,
+		"distance", "d", "", "Set distance metric (mandatory)")
+	selectivitySweepCommand.PersistentFlags().BoolVarP(&globalConfig.QueryOnly,
+		"query", "q", false, "Do not import data and only run query tests")
+	selectivitySweepCommand.PersistentFlags().StringVar(&globalConfig.IndexType,
+		"indexType", "hnsw", "Index type (hnsw or flat)")
+	selectivitySweepCommand.PersistentFlags().StringVar(&globalConfig.FilterStrategy,
+		"filterStrategy", "sweeping", "Use a different filter strategy (options are sweeping or acorn)")
+	selectivitySweepCommand.PersistentFlags().IntVar(&globalConfig.FlatSearchCutoff,
+		"flatSearchCutoff", 40000, "Flat search cut off (default 40 000)")
+	selectivitySweepCommand.PersistentFlags().IntVar(&globalConfig.EfConstruction,
+		"efConstruction", 256, "Set Weaviate efConstruction parameter (default 256)")
+	selectivitySweepCommand.PersistentFlags().IntVar(&globalConfig.MaxConnections,
+		"maxConnections", 16, "Set Weaviate efConstruction parameter (default 16)")
+	selectivitySweepCommand.PersistentFlags().IntVar(&globalConfig.CleanupIntervalSeconds,
+		"cleanupIntervalSeconds", 300, "HNSW cleanup interval seconds (default 300)")
+	selectivitySweepCommand.PersistentFlags().StringVar(&globalConfig.EfArray,
+		"efArray", "16,32,64,128,256", "Array of ef parameters as comma separated list")
+	selectivitySweepCommand.PersistentFlags().IntVar(&globalConfig.Shards,
+		"shards", 1, "Set number of Weaviate shards")
+	selectivitySweepCommand.PersistentFlags().IntVarP(&globalConfig.BatchSize,
+		"batchSize", "b", 1000, "Batch size for insert operations")
+	selectivitySweepCommand.PersistentFlags().IntVar(&globalConfig.ImportParallel,
+		"importParallel", 8, "Number of parallel workers sending import batches")
+	selectivitySweepCommand.PersistentFlags().BoolVar(&globalConfig.SkipAsyncReady,
+		"skipAsyncReady", false, "Skip async ready (default false)")
+	selectivitySweepCommand.PersistentFlags().IntVarP(&globalConfig.Parallel,
+		"parallel", "p", numCPU, "Set the number of parallel threads which send queries and compute the ground truth")
+	selectivitySweepCommand.PersistentFlags().IntVarP(&globalConfig.Limit,
+		"limit", "l", 10, "Set the query limit / k (default 10)")
+	selectivitySweepCommand.PersistentFlags().Float64Var(&globalConfig.RecallEpsilon,
+		"recallEpsilon", 1e-3, "Results at most this much farther than the k-th neighbor count for recall")
+	selectivitySweepCommand.PersistentFlags().IntVar(&globalConfig.MaxQueries,
+		"maxQueries", 0, "Only read and query the first n test vectors, or a random sample of n with --sampleQueries (default 0, all)")
+	selectivitySweepCommand.PersistentFlags().BoolVar(&globalConfig.SampleQueries,
+		"sampleQueries", false, "Query a random sample of --maxQueries test vectors instead of the first ones")
+	selectivitySweepCommand.PersistentFlags().Int64Var(&globalConfig.Seed,
+		"seed", 0, "Seed of the --sampleQueries sample")
+	selectivitySweepCommand.PersistentFlags().StringVarP(&globalConfig.API,
+		"api", "a", "grpc", "The API to use on benchmarks")
+	selectivitySweepCommand.PersistentFlags().StringVarP(&globalConfig.Origin,
+		"grpcOrigin", "u", "localhost:50051", "The gRPC origin that Weaviate is running at")
+	selectivitySweepCommand.PersistentFlags().StringVar(&globalConfig.HttpOrigin,
+		"httpOrigin", "localhost:8080", "The http origin for Weaviate (only used if grpc enabled)")
+	selectivitySweepCommand.PersistentFlags().StringVar(&globalConfig.HttpScheme,
+		"httpScheme", "http", "The http scheme (http or https)")
+}
+
+// Parse the comma separated selectivities into the cardinalities of their
+// properties, in ascending order of selectivity
+func parseSelectivities(s string) ([]int, error) {
+	var cardinalities []int
+	for _, field := range strings.Split(s, ",") {
+		selectivity, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid selectivity %q", field)
+		}
+		if selectivity <= 0 || selectivity > 1 {
+			return nil, errors.Errorf("selectivity %v must be in (0, 1]", selectivity)
+		}
+		cardinality := max(1, int(1/selectivity+0.5))
+		if !slices.Contains(cardinalities, cardinality) {
+			cardinalities = append(cardinalities, cardinality)
+		}
+	}
+	slices.Sort(cardinalities)
+	slices.Reverse(cardinalities)
+	return cardinalities, nil
+}
+
+func cardinalityProperty(cardinality int) string {
+	return fmt.Sprintf("cardinality%d", cardinality)
+}
+
+// Pseudo-random value of a row for a property of the given cardinality,
+// values of neighboring rows and of different properties are independent.
+// Test rows use a different salt than train rows.
+func cardinalityValue(row, cardinality int, salt uint64) int {
+	// splitmix64
+	z := uint64(row)*0x9e3779b97f4a7c15 + uint64(cardinality)*0xbf58476d1ce4e5b9 + salt
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return int(z % uint64(cardinality))
+}
+
+const (
+	trainCardinalitySalt = 0
+	testCardinalitySalt  = 0x5851f42d4c957f2d
+)
+
+// The test set of one selectivity, every test vector filters on its own value
+// of the property
+type selectivityLevel struct {
+	cardinality int
+	test        *testSet
+}
+
+// Compute the filtered ground truth of every selectivity, the train vectors
+// are scanned once per selectivity
+func loadSelectivityLevels(source datasetSource, cfg *Config) []selectivityLevel {
+	distance, err := newDistanceFunc(cfg.DistanceMetric)
+	if err != nil {
+		log.Fatalf("Error computing ground truth: %v", err)
+	}
+
+	testRows := queryRows(cfg, source.testCount(cfg))
+	vectors := source.testVectors(cfg, testRows)
+	if testRows == nil {
+		testRows = make([]int, len(vectors))
+		for i := range testRows {
+			testRows[i] = i
+		}
+	}
+	rows, _ := source.trainExtent(cfg)
+
+	levels := make([]selectivityLevel, len(cfg.SelectivityCardinalities))
+	for l, cardinality := range cfg.SelectivityCardinalities {
+		trainValues := make([]int, rows)
+		for row := range trainValues {
+			trainValues[row] = cardinalityValue(row, cardinality, trainCardinalitySalt)
+		}
+		testValues := make([]int, len(vectors))
+		filters := make([]*weaviategrpc.Filters, len(vectors))
+		for i, row := range testRows {
+			testValues[i] = cardinalityValue(row, cardinality, testCardinalitySalt)
+			filters[i] = &weaviategrpc.Filters{
+				Operator:  weaviategrpc.Filters_OPERATOR_EQUAL,
+				On:        []string{cardinalityProperty(cardinality)},
+				TestValue: &weaviategrpc.Filters_ValueText{ValueText: strconv.Itoa(testValues[i])},
+			}
+		}
+
+		startTime := time.Now()
+		neighbors, distances := computeGroundTruth(source, cfg, vectors, trainValues, testValues, distance)
+		log.WithFields(log.Fields{"selectivity": 1 / float64(cardinality), "queries": len(vectors),
+			"duration": time.Since(startTime)}).Info("Computed filtered ground truth")
+
+		levels[l] = selectivityLevel{
+			cardinality: cardinality,
+			test:        &testSet{vectors: vectors, neighbors: neighbors, distances: distances, filters: filters},
+		}
+	}
+	return levels
+}
+
+// Query every selectivity at every ef, the ef is only updated once for all
+// selectivities
+func runSelectivitySweep(cfg *Config, levels []selectivityLevel) {
+	runID := strconv.FormatInt(time.Now().Unix(), 10)
+
+	efCandidates, err := parseEfValues(cfg.EfArray)
+	if err != nil {
+		log.Fatalf("Error parsing efArray, expected commas separated format \"16,32,64\" but:%v\n", err)
+	}
+
+	client := createClient(cfg)
+
+	matrix := make([][]Results, len(levels))
+	for l := range matrix {
+		matrix[l] = make([]Results, len(efCandidates))
+	}
+
+	var benchmarkResultsMap []map[string]interface{}
+	for e, ef := range efCandidates {
+		updateEf(ef, cfg, client)
+		setPhase("query", ef)
+
+		for l, level := range levels {
+			result := benchmarkANN(*cfg, level.test)
+			matrix[l][e] = result
+
+			selectivity := 1 / float64(level.cardinality)
+			log.WithFields(log.Fields{
+				"ef": ef, "selectivity": selectivity, "qps": result.QueriesPerSecond,
+				"recall": result.Recall, "p99": result.Percentile(99), "failed": result.Failed,
+			}).Info("Selectivity result")
+
+			benchResult := newResultsJSONBenchmark(cfg, ef, result, 0, runID, &Memstats{})
+			resultMap := benchResult.toMap(cfg)
+			resultMap["selectivity"] = selectivity
+			resultMap["filterStrategy"] = cfg.FilterStrategy
+			benchmarkResultsMap = append(benchmarkResultsMap, resultMap)
+		}
+	}
+
+	writeBenchmarkResults(runID, benchmarkResultsMap)
+	writeSelectivityMatrix(os.Stdout, levels, efCandidates, matrix)
+}
+
+// Print the QPS and recall of every selectivity (rows) and ef (columns)
+func writeSelectivityMatrix(w io.Writer, levels []selectivityLevel, efCandidates []int, matrix [][]Results) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := "selectivity\t"
+	for _, ef := range efCandidates {
+		header += fmt.Sprintf("ef=%d qps\trecall\t", ef)
+	}
+	fmt.Fprintln(tw, header)
+	for l, level := range levels {
+		line := fmt.Sprintf("%g%%\t", 100/float64(level.cardinality))
+		for _, result := range matrix[l] {
+			line += fmt.Sprintf("%.1f\t%.4f\t", result.QueriesPerSecond, result.Recall)
+		}
+		fmt.Fprintln(tw, line)
+	}
+	tw.Flush()
+}
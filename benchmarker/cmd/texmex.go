@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// A TEXMEX .fvecs/.ivecs/.bvecs file (http://corpus-texmex.irisa.fr). Every
+// vector is stored as a little endian int32 dimension followed by the
+// components as float32, int32 or uint8.
+type vecsFile struct {
+	file          *os.File
+	path          string
+	componentSize int
+	integer       bool
+	dimensions    int
+	rows          int
+}
+
+func openVecsFile(path string) (*vecsFile, error) {
+	v := &vecsFile{path: path}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".fvecs":
+		v.componentSize = 4
+	case ".ivecs":
+		v.componentSize = 4
+		v.integer = true
+	case ".bvecs":
+		v.componentSize = 1
+	default:
+		return nil, errors.Errorf("%s: unsupported extension, expected .fvecs, .ivecs or .bvecs", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	v.file = f
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	header := make([]byte, 4)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "%s: read dimensions", path)
+	}
+	v.dimensions = int(binary.LittleEndian.Uint32(header))
+	if v.dimensions <= 0 {
+		f.Close()
+		return nil, errors.Errorf("%s: invalid dimensions %d", path, v.dimensions)
+	}
+
+	rowSize := int64(v.rowSize())
+	if stat.Size()%rowSize != 0 {
+		f.Close()
+		return nil, errors.Errorf("%s: size %d is not a multiple of the row size %d", path, stat.Size(), rowSize)
+	}
+	v.rows = int(stat.Size() / rowSize)
+
+	return v, nil
+}
+
+func (v *vecsFile) rowSize() int {
+	return 4 + v.dimensions*v.componentSize
+}
+
+// Read count rows starting at row start
+func (v *vecsFile) read(start, count int) ([]byte, error) {
+	buf := make([]byte, count*v.rowSize())
+	if n, err := v.file.ReadAt(buf, int64(start)*int64(v.rowSize())); n < len(buf) {
+		return nil, errors.Errorf("%s: read rows %d to %d: %v", v.path, start, start+count, err)
+	}
+
+	for i := 0; i < count; i++ {
+		if d := int(binary.LittleEndian.Uint32(buf[i*v.rowSize():])); d != v.dimensions {
+			return nil, errors.Errorf("%s: row %d has %d dimensions, expected %d", v.path, start+i, d, v.dimensions)
+		}
+	}
+	return buf, nil
+}
+
+// Read rows as vectors, uint8 and int32 components are converted to float32
+func (v *vecsFile) float32Rows(start, count int) ([][]float32, error) {
+	buf, err := v.read(start, count)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([][]float32, count)
+	for i := range out {
+		row := buf[i*v.rowSize()+4 : (i+1)*v.rowSize()]
+		out[i] = make([]float32, v.dimensions)
+		for j := range out[i] {
+			switch {
+			case v.componentSize == 1:
+				out[i][j] = float32(row[j])
+			case v.integer:
+				out[i][j] = float32(int32(binary.LittleEndian.Uint32(row[j*4:])))
+			default:
+				out[i][j] = math.Float32frombits(binary.LittleEndian.Uint32(row[j*4:]))
+			}
+		}
+	}
+	return out, nil
+}
+
+// Read rows of an .ivecs file, e.g. ground truth neighbors
+func (v *vecsFile) intRows(start, count int) ([][]int, error) {
+	if !v.integer {
+		return nil, errors.Errorf("%s: expected an .ivecs file", v.path)
+	}
+	buf, err := v.read(start, count)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([][]int, count)
+	for i := range out {
+		row := buf[i*v.rowSize()+4 : (i+1)*v.rowSize()]
+		out[i] = make([]int, v.dimensions)
+		for j := range out[i] {
+			out[i][j] = int(int32(binary.LittleEndian.Uint32(row[j*4:])))
+		}
+	}
+	return out, nil
+}
+
+func (v *vecsFile) Close() {
+	v.file.Close()
+}
+
+// A TEXMEX dataset, the base vectors are streamed from cfg.BenchmarkFile.
+// Test vectors and neighbors default to the <name>_query (.fvecs or .bvecs)
+// and <name>_groundtruth.ivecs files next to a <name>_base file.
+type texmexSource struct {
+	train         *vecsFile
+	testPath      string
+	neighborsPath string
+}
+
+func openTexmexSource(cfg *Config) *texmexSource {
+	if cfg.MultiVectorDimensions > 0 {
+		log.Fatalf("multi-vector datasets are only supported in hdf5 format")
+	}
+
+	train, err := openVecsFile(cfg.BenchmarkFile)
+	if err != nil {
+		log.Fatalf("Error opening dataset: %v", err)
+	}
+
+	s := &texmexSource{
+		train:         train,
+		testPath:      cfg.TestVectorsFile,
+		neighborsPath: cfg.NeighborsFile,
+	}
+
+	dir, base := filepath.Split(cfg.BenchmarkFile)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(strings.TrimSuffix(base, ext), "_base")
+	if s.testPath == "" {
+		s.testPath = filepath.Join(dir, name+"_query"+ext)
+		if _, err := os.Stat(s.testPath); err != nil {
+			for _, queryExt := range []string{".fvecs", ".bvecs"} {
+				candidate := filepath.Join(dir, name+"_query"+queryExt)
+				if _, err := os.Stat(candidate); err == nil {
+					s.testPath = candidate
+					break
+				}
+			}
+		}
+	}
+	if s.neighborsPath == "" {
+		s.neighborsPath = filepath.Join(dir, name+"_groundtruth.ivecs")
+	}
+
+	return s
+}
+
+func (s *texmexSource) trainExtent(cfg *Config) (uint, uint) {
+	return uint(s.train.rows), uint(s.train.dimensions)
+}
+
+func (s *texmexSource) streamTrain(chunks chan<- Batch, cfg *Config, startOffset uint, maxRecords uint, filters []int) {
+	rows := uint(s.train.rows)
+
+	i := uint(0)
+	if maxRecords != 0 && maxRecords < rows {
+		rows = maxRecords
+	}
+
+	if startOffset != 0 && i < rows {
+		i = startOffset
+	}
+
+	batchSize := uint(cfg.BatchSize)
+
+	log.WithFields(log.Fields{"rows": rows, "dimensions": s.train.dimensions}).Printf(
+		"Reading TEXMEX dataset")
+
+	for ; i < rows; i += batchSize {
+		batchRows := min(batchSize, rows-i)
+
+		chunkData, err := s.train.float32Rows(int(i), int(batchRows))
+		if err != nil {
+			log.Fatalf("Error reading dataset: %v", err)
+		}
+
+		chunks <- Batch{Vectors: chunkData, Offset: int(i), Filters: []int{}}
+	}
+}
+
+func (s *texmexSource) openTest() *vecsFile {
+	test, err := openVecsFile(s.testPath)
+	if err != nil {
+		log.Fatalf("Error opening test vectors, set --testVectors: %v", err)
+	}
+	return test
+}
+
+func (s *texmexSource) testCount(cfg *Config) int {
+	test := s.openTest()
+	defer test.Close()
+	return test.rows
+}
+
+func (s *texmexSource) testVectors(cfg *Config, rows []int) [][]float32 {
+	test := s.openTest()
+	defer test.Close()
+
+	if test.dimensions != s.train.dimensions {
+		log.Fatalf("Test vectors have %d dimensions, base vectors %d", test.dimensions, s.train.dimensions)
+	}
+
+	vectors, err := readRowRuns(rows, test.rows, test.float32Rows)
+	if err != nil {
+		log.Fatalf("Error reading test vectors: %v", err)
+	}
+	return vectors
+}
+
+func (s *texmexSource) neighbors(cfg *Config, rows []int) [][]int {
+	gt, err := openVecsFile(s.neighborsPath)
+	if err != nil {
+		log.Fatalf("Error opening neighbors, set --neighbors: %v", err)
+	}
+	defer gt.Close()
+
+	neighbors, err := readRowRuns(rows, gt.rows, gt.intRows)
+	if err != nil {
+		log.Fatalf("Error reading neighbors: %v", err)
+	}
+	return neighbors
+}
+
+func (s *texmexSource) distances(cfg *Config, rows []int) [][]float32 {
+	return nil
+}
+
+func (s *texmexSource) categories(name string) []int {
+	log.Fatalf("TEXMEX datasets have no %s, filtering requires an hdf5 dataset", name)
+	return nil
+}
+
+func (s *texmexSource) Close() {
+	s.train.Close()
+}
@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// Timeline histograms use 2 significant digits, they are only used for
+// coarse per interval percentiles and there is one per interval
+const timelineSignificant = 2
+
+// Splits a run into fixed intervals by query completion time. Workers keep
+// their current interval in their latencyRecorder and only merge it into the
+// shared timeline once they move on to the next interval, so the lock is
+// taken about once per worker and interval.
+type timeline struct {
+	sync.Mutex
+	start    time.Time
+	interval time.Duration
+	buckets  []*timelineBucket
+
+	// Number of recent intervals kept if only the progress output needs
+	// them, 0 keeps all. Dropped buckets are reused.
+	retain  int
+	dropped int
+	free    []*timelineBucket
+}
+
+type timelineBucket struct {
+	histogram   *hdrhistogram.Histogram
+	count       int
+	errors      int
+	recallSum   float64
+	recallCount int
+}
+
+// One interval of a run as written to the timeline file, latencies in seconds
+type TimelineRecord struct {
+	Ef        int       `json:"ef"`
+	Timestamp time.Time `json:"timestamp"`
+	Elapsed   float64   `json:"elapsed"`
+	Queries   int       `json:"queries"`
+	Errors    int       `json:"errors"`
+	QPS       float64   `json:"qps"`
+	Recall    float64   `json:"recall"`
+	Mean      float64   `json:"meanLatency"`
+	P50       float64   `json:"p50Latency"`
+	P90       float64   `json:"p90Latency"`
+	P99       float64   `json:"p99Latency"`
+	Max       float64   `json:"maxLatency"`
+}
+
+// Returns nil if the timeline is disabled
+func newTimeline(interval time.Duration) *timeline {
+	if interval <= 0 {
+		return nil
+	}
+	return &timeline{start: time.Now(), interval: interval}
+}
+
+// A timeline that only keeps the last n intervals, enough for the progress
+// output of a run without --timelineInterval
+func newRollingTimeline(interval time.Duration, n int) *timeline {
+	t := newTimeline(interval)
+	t.retain = n
+	return t
+}
+
+func newTimelineBucket() *timelineBucket {
+	return &timelineBucket{
+		histogram: hdrhistogram.New(histogramLowestLatency, histogramHighestLatency, timelineSignificant),
+	}
+}
+
+func (t *timeline) index(now time.Time) int {
+	return int(now.Sub(t.start) / t.interval)
+}
+
+// Merge the interval a worker collected into the timeline
+func (t *timeline) merge(idx int, b *timelineBucket) {
+	t.Lock()
+	defer t.Unlock()
+
+	for len(t.buckets) <= idx {
+		t.buckets = append(t.buckets, nil)
+	}
+	if t.buckets[idx] == nil {
+		t.buckets[idx] = t.newBucket()
+	}
+
+	t.buckets[idx].add(b)
+
+	if t.retain > 0 {
+		for ; t.dropped < idx-t.retain; t.dropped++ {
+			if old := t.buckets[t.dropped]; old != nil {
+				old.reset()
+				t.free = append(t.free, old)
+				t.buckets[t.dropped] = nil
+			}
+		}
+	}
+}
+
+func (t *timeline) newBucket() *timelineBucket {
+	if n := len(t.free); n > 0 {
+		b := t.free[n-1]
+		t.free = t.free[:n-1]
+		return b
+	}
+	return newTimelineBucket()
+}
+
+func (b *timelineBucket) reset() {
+	b.histogram.Reset()
+	b.count = 0
+	b.errors = 0
+	b.recallSum = 0
+	b.recallCount = 0
+}
+
+func (b *timelineBucket) add(other *timelineBucket) {
+	b.histogram.Merge(other.histogram)
+	b.count += other.count
+	b.errors += other.errors
+	b.recallSum += other.recallSum
+	b.recallCount += other.recallCount
+}
+
+// Summarize a bucket that covers the given duration
+func (b *timelineBucket) summarize(duration time.Duration) TimelineRecord {
+	r := TimelineRecord{Queries: b.count, Errors: b.errors}
+	if duration > 0 {
+		r.QPS = float64(b.count) / duration.Seconds()
+	}
+	if b.recallCount > 0 {
+		r.Recall = b.recallSum / float64(b.recallCount)
+	}
+	if b.count > 0 {
+		r.Mean = (time.Duration(b.histogram.Mean()) * time.Microsecond).Seconds()
+		r.P50 = histogramPercentile(b.histogram, 50).Seconds()
+		r.P90 = histogramPercentile(b.histogram, 90).Seconds()
+		r.P99 = histogramPercentile(b.histogram, 99).Seconds()
+		r.Max = (time.Duration(b.histogram.Max()) * time.Microsecond).Seconds()
+	}
+	return r
+}
+
+// Summarize all intervals, end marks the end of the run so the last
+// (partial) interval reports the right QPS
+func (t *timeline) records(end time.Time) []TimelineRecord {
+	if t == nil {
+		return nil
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	records := make([]TimelineRecord, len(t.buckets))
+	for i, b := range t.buckets {
+		bucketStart := t.start.Add(time.Duration(i) * t.interval)
+		duration := t.interval
+		if remaining := end.Sub(bucketStart); remaining < duration && remaining > 0 {
+			duration = remaining
+		}
+
+		if b != nil {
+			records[i] = b.summarize(duration)
+		}
+		records[i].Timestamp = bucketStart
+	}
+
+	return records
+}
+
+// Summarize the last n complete intervals into one record, the interval in
+// progress is left out as workers only merge an interval once it is over
+func (t *timeline) window(n int) TimelineRecord {
+	t.Lock()
+	defer t.Unlock()
+
+	current := t.index(time.Now())
+	from := max(current-n, 0)
+
+	merged := newTimelineBucket()
+	for i := from; i < current && i < len(t.buckets); i++ {
+		if t.buckets[i] != nil {
+			merged.add(t.buckets[i])
+		}
+	}
+
+	return merged.summarize(time.Duration(current-from) * t.interval)
+}
+
+// Write the timeline of a run next to ./results/<runID>.json, elapsed time
+// is relative to start so the intervals of all ef values line up
+func writeTimeline(cfg *Config, runID string, start time.Time, records []TimelineRecord) {
+	if len(records) == 0 {
+		return
+	}
+
+	for i := range records {
+		records[i].Elapsed = records[i].Timestamp.Sub(start).Seconds()
+	}
+
+	os.Mkdir("./results", 0o755)
+
+	path := fmt.Sprintf("./results/%s.timeline.%s", runID, cfg.TimelineFormat)
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Error creating timeline file: %v", err)
+	}
+	defer f.Close()
+
+	if cfg.TimelineFormat == "csv" {
+		err = writeTimelineCSV(f, records)
+	} else {
+		err = writeTimelineJSONL(f, records)
+	}
+	if err != nil {
+		log.Fatalf("Error writing timeline to file: %v", err)
+	}
+}
+
+func writeTimelineJSONL(w io.Writer, records []TimelineRecord) error {
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTimelineCSV(w io.Writer, records []TimelineRecord) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"ef", "timestamp", "elapsed", "queries", "errors", "qps", "recall",
+		"meanLatency", "p50Latency", "p90Latency", "p99Latency", "maxLatency"})
+
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	for _, r := range records {
+		cw.Write([]string{
+			strconv.Itoa(r.Ef), r.Timestamp.Format(time.RFC3339Nano), formatFloat(r.Elapsed),
+			strconv.Itoa(r.Queries), strconv.Itoa(r.Errors), formatFloat(r.QPS), formatFloat(r.Recall),
+			formatFloat(r.Mean), formatFloat(r.P50), formatFloat(r.P90), formatFloat(r.P99), formatFloat(r.Max),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}